@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/viper"
+)
+
+// loadAWSConfig loads the default AWS config and applies the profile, region and
+// role flags. If a role is given its credentials are assumed using the credentials
+// of the default config or profile.
+func loadAWSConfig(ctx context.Context) (aws.Config, error) {
+	opts := []func(*config.LoadOptions) error{}
+	if viper.GetString(profile) != "" {
+		opts = append(opts, config.WithSharedConfigProfile(viper.GetString(profile)))
+	}
+	if viper.GetString(region) != "" {
+		opts = append(opts, config.WithRegion(viper.GetString(region)))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return cfg, err
+	}
+
+	if viper.GetString(roleArn) != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), viper.GetString(roleArn), func(o *stscreds.AssumeRoleOptions) {
+			if viper.GetString(externalID) != "" {
+				o.ExternalID = aws.String(viper.GetString(externalID))
+			}
+			if viper.GetString(roleSessionName) != "" {
+				o.RoleSessionName = viper.GetString(roleSessionName)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return cfg, nil
+}
+
+// newClient returns the CloudWatch Logs client using the endpoint flag if given.
+func newClient(cfg aws.Config) *cloudwatchlogs.Client {
+	return cloudwatchlogs.NewFromConfig(cfg, func(o *cloudwatchlogs.Options) {
+		if viper.GetString(endpointURL) != "" {
+			o.BaseEndpoint = aws.String(viper.GetString(endpointURL))
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadAWSConfig(t *testing.T) {
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+	t.Cleanup(viper.Reset)
+
+	t.Run("with region", func(t *testing.T) {
+		viper.Set(region, "eu-central-1")
+		cfg, err := loadAWSConfig(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "eu-central-1", cfg.Region)
+		viper.Reset()
+	})
+	t.Run("with unknown profile", func(t *testing.T) {
+		viper.Set(profile, "does-not-exist")
+		_, err := loadAWSConfig(context.Background())
+		assert.Error(t, err)
+		viper.Reset()
+	})
+	t.Run("with role", func(t *testing.T) {
+		viper.Set(region, "eu-central-1")
+		viper.Set(roleArn, "arn:aws:iam::123456789012:role/test")
+		viper.Set(externalID, "external")
+		viper.Set(roleSessionName, "lc")
+		cfg, err := loadAWSConfig(context.Background())
+		assert.NoError(t, err)
+		assert.IsType(t, &aws.CredentialsCache{}, cfg.Credentials)
+		viper.Reset()
+	})
+}
+
+func TestNewClient(t *testing.T) {
+	t.Cleanup(viper.Reset)
+
+	t.Run("default endpoint", func(t *testing.T) {
+		client := newClient(aws.Config{Region: "eu-central-1"})
+		assert.Nil(t, client.Options().BaseEndpoint)
+	})
+	t.Run("with endpoint", func(t *testing.T) {
+		viper.Set(endpointURL, "http://localhost:4566")
+		client := newClient(aws.Config{Region: "eu-central-1"})
+		assert.Equal(t, "http://localhost:4566", *client.Options().BaseEndpoint)
+		viper.Reset()
+	})
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/spf13/viper"
+)
+
+// checkpoint is saved while logs are written to a file, so an export can be
+// resumed with the same parameters from the last completely written page.
+type checkpoint struct {
+	OutputFile string `json:"output-file"`
+	outputSettings
+	Written int                 `json:"written"`
+	Sources []*sourceCheckpoint `json:"sources"`
+}
+
+// outputSettings are the flags which change what is written for an event. They
+// are restored when resuming, so the format doesn't change within a file.
+type outputSettings struct {
+	OutputFormat string   `json:"output-format"`
+	FilterFields []string `json:"filter-fields,omitempty"`
+	Project      string   `json:"project,omitempty"`
+	Where        string   `json:"where,omitempty"`
+	SplitBy      string   `json:"split-by,omitempty"`
+	Template     string   `json:"template,omitempty"`
+	Tz           string   `json:"tz,omitempty"`
+	TimeFormat   string   `json:"time-format,omitempty"`
+}
+
+// currentOutputSettings returns the output settings given by flags.
+func currentOutputSettings() outputSettings {
+	return outputSettings{
+		OutputFormat: viper.GetString(outputFormat),
+		FilterFields: viper.GetStringSlice(filterFields),
+		Project:      viper.GetString(project),
+		Where:        viper.GetString(where),
+		SplitBy:      viper.GetString(splitBy),
+		Template:     viper.GetString(templateFlag),
+		Tz:           viper.GetString(tz),
+		TimeFormat:   viper.GetString(timeFormat),
+	}
+}
+
+// restore sets the flags to the saved output settings.
+func (s outputSettings) restore() {
+	viper.Set(outputFormat, s.OutputFormat)
+	viper.Set(filterFields, s.FilterFields)
+	viper.Set(project, s.Project)
+	viper.Set(where, s.Where)
+	viper.Set(splitBy, s.SplitBy)
+	viper.Set(templateFlag, s.Template)
+	viper.Set(tz, s.Tz)
+	viper.Set(timeFormat, s.TimeFormat)
+}
+
+// sourceCheckpoint contains the query parameters of a log source, the token of the
+// page which is currently written and the ids of the events already written from
+// this page.
+type sourceCheckpoint struct {
+	LogGroupName        *string  `json:"log-group-name"`
+	FilterPattern       *string  `json:"filter-pattern,omitempty"`
+	LogStreamNamePrefix *string  `json:"logstream-prefix,omitempty"`
+	LogStreamNames      []string `json:"logstream-names,omitempty"`
+	StartTime           *int64   `json:"start-time,omitempty"`
+	EndTime             *int64   `json:"end-time,omitempty"`
+	Limit               *int32   `json:"limit,omitempty"`
+	Tag                 bool     `json:"tag,omitempty"`
+	Last                bool     `json:"last,omitempty"`
+	NextToken           *string  `json:"next-token,omitempty"`
+	EventIds            []string `json:"event-ids,omitempty"`
+	Done                bool     `json:"done,omitempty"`
+}
+
+func newSourceCheckpoint(input *cloudwatchlogs.FilterLogEventsInput) *sourceCheckpoint {
+	return &sourceCheckpoint{
+		LogGroupName:        input.LogGroupName,
+		FilterPattern:       input.FilterPattern,
+		LogStreamNamePrefix: input.LogStreamNamePrefix,
+		LogStreamNames:      input.LogStreamNames,
+		StartTime:           input.StartTime,
+		EndTime:             input.EndTime,
+		Limit:               input.Limit,
+		NextToken:           input.NextToken,
+	}
+}
+
+// written records that the event with the given id of the page fetched with token
+// was written.
+func (p *sourceCheckpoint) written(token *string, id string) {
+	if p == nil {
+		return
+	}
+	if aws.ToString(token) != aws.ToString(p.NextToken) {
+		p.NextToken = token
+		p.EventIds = nil
+	}
+	p.EventIds = append(p.EventIds, id)
+}
+
+// pageDone records that all events of the current page were written.
+func (p *sourceCheckpoint) pageDone(nextToken *string) {
+	if p == nil {
+		return
+	}
+	p.NextToken = nextToken
+	p.EventIds = nil
+	p.Done = nextToken == nil
+}
+
+func newCheckpoint(outputFile string, settings outputSettings, sources []*logSource) *checkpoint {
+	cp := &checkpoint{
+		OutputFile:     outputFile,
+		outputSettings: settings,
+	}
+	for _, source := range sources {
+		source.progress.Tag = source.tag
+		source.progress.Last = source.last
+		cp.Sources = append(cp.Sources, source.progress)
+	}
+	return cp
+}
+
+// logSources returns the sources which are not done yet, starting at the saved
+// page and skipping the events already written.
+func (cp *checkpoint) logSources() []*logSource {
+	sources := []*logSource{}
+	for _, progress := range cp.Sources {
+		if progress.Done {
+			continue
+		}
+		skip := map[string]bool{}
+		for _, id := range progress.EventIds {
+			skip[id] = true
+		}
+		source := &logSource{
+			input: &cloudwatchlogs.FilterLogEventsInput{
+				LogGroupName:        progress.LogGroupName,
+				FilterPattern:       progress.FilterPattern,
+				LogStreamNamePrefix: progress.LogStreamNamePrefix,
+				LogStreamNames:      progress.LogStreamNames,
+				StartTime:           progress.StartTime,
+				EndTime:             progress.EndTime,
+				Limit:               progress.Limit,
+				NextToken:           progress.NextToken,
+			},
+			tracker:  newEventTracker(),
+			tag:      progress.Tag,
+			last:     progress.Last,
+			buffer:   defaultBuffer,
+			skip:     skip,
+			progress: progress,
+		}
+		if len(cp.Sources) > 1 {
+			source.setShardBuffer(len(cp.Sources))
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// save writes the checkpoint to file.
+func (cp *checkpoint) save(file string) error {
+	return writeJSONFile(file, cp)
+}
+
+// writeJSONFile writes v to a temporary file first and renames it afterwards, so a
+// crash while saving doesn't destroy the last version of file.
+func writeJSONFile(file string, v interface{}) error {
+	bt, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(bt); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+func loadCheckpoint(file string) (*checkpoint, error) {
+	bt, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cp := &checkpoint{}
+	if err := json.Unmarshal(bt, cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", file, err)
+	}
+	return cp, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/spf13/viper"
+	"github.com/steffakasid/lc/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func checkpointTestEvents() *internal.FakeClient {
+	return fakeClient(map[string][]types.FilteredLogEvent{
+		"a": {testEvent("1", 1), testEvent("2", 2), testEvent("3", 3), testEvent("4", 4), testEvent("5", 5)},
+	})
+}
+
+func TestSourceCheckpoint(t *testing.T) {
+	progress := newSourceCheckpoint(&cloudwatchlogs.FilterLogEventsInput{LogGroupName: aws.String("a")})
+	progress.written(nil, "1")
+	progress.written(nil, "2")
+	assert.Equal(t, []string{"1", "2"}, progress.EventIds)
+	progress.pageDone(aws.String("3"))
+	assert.Equal(t, "3", *progress.NextToken)
+	assert.Empty(t, progress.EventIds)
+	progress.written(aws.String("3"), "3")
+	assert.Equal(t, []string{"3"}, progress.EventIds)
+	progress.written(aws.String("5"), "5")
+	assert.Equal(t, "5", *progress.NextToken)
+	assert.Equal(t, []string{"5"}, progress.EventIds)
+	progress.pageDone(nil)
+	assert.True(t, progress.Done)
+
+	var nilProgress *sourceCheckpoint
+	nilProgress.written(nil, "1")
+	nilProgress.pageDone(nil)
+}
+
+func TestCheckpointSaveAndLoad(t *testing.T) {
+	file := path.Join(t.TempDir(), "checkpoint.json")
+	sources := newLogSources([]string{"a", "b"}, &cloudwatchlogs.FilterLogEventsInput{
+		FilterPattern: aws.String("error"),
+		StartTime:     aws.Int64(0),
+		EndTime:       aws.Int64(99),
+	}, 2)
+	settings := outputSettings{
+		OutputFormat: "yaml",
+		FilterFields: []string{"log"},
+		Project:      "{log}",
+		Where:        `level == "error"`,
+		SplitBy:      "stream",
+		Template:     "{{.EventId}}",
+		Tz:           "UTC",
+		TimeFormat:   "kitchen",
+	}
+	cp := newCheckpoint("logs.txt", settings, sources)
+	cp.Written = 42
+	sources[0].progress.pageDone(nil)
+	sources[1].progress.written(aws.String("token"), "id")
+	assert.NoError(t, cp.save(file))
+
+	loaded, err := loadCheckpoint(file)
+	assert.NoError(t, err)
+	assert.Equal(t, cp, loaded)
+	t.Cleanup(viper.Reset)
+	loaded.restore()
+	assert.Equal(t, settings, currentOutputSettings())
+
+	resumed := loaded.logSources()
+	assert.Len(t, resumed, 3)
+	assert.Equal(t, "token", *resumed[0].input.NextToken)
+	assert.Equal(t, "error", *resumed[0].input.FilterPattern)
+	assert.Equal(t, int64(50), *resumed[0].input.StartTime)
+	assert.True(t, resumed[0].skip["id"])
+	assert.True(t, resumed[0].tag)
+	assert.True(t, resumed[0].last)
+
+	_, err = loadCheckpoint(path.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestResumeAfterError(t *testing.T) {
+	file := path.Join(t.TempDir(), "checkpoint.json")
+	written := []string{}
+	handle := func(log internal.Log) {
+		written = append(written, *log.EventId)
+	}
+
+	client := checkpointTestEvents()
+	client.Err = internal.ErrFakeConnection
+	client.FailAfter = 1
+	sources := newLogSources([]string{"a"}, &cloudwatchlogs.FilterLogEventsInput{}, 1)
+	cp := newCheckpoint("logs.txt", outputSettings{OutputFormat: "txt"}, sources)
+	err := fetchLogs(context.Background(), client, sources, handle, func() {
+		cp.Written = len(written)
+		assert.NoError(t, cp.save(file))
+	})
+	assert.EqualError(t, err, "fetching a: connection reset by peer")
+	assert.Equal(t, []string{"1", "2"}, written)
+
+	loaded, err := loadCheckpoint(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, loaded.Written)
+	err = fetchLogs(context.Background(), checkpointTestEvents(), loaded.logSources(), handle, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, written)
+}
+
+func TestResumeSkipsWrittenEvents(t *testing.T) {
+	cp := &checkpoint{Sources: []*sourceCheckpoint{{
+		LogGroupName: aws.String("a"),
+		NextToken:    aws.String("2"),
+		EventIds:     []string{"3"},
+	}, {
+		LogGroupName: aws.String("b"),
+		Done:         true,
+	}}}
+	written := []string{}
+	err := fetchLogs(context.Background(), checkpointTestEvents(), cp.logSources(), func(log internal.Log) {
+		written = append(written, *log.EventId)
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4", "5"}, written)
+	assert.True(t, cp.Sources[0].Done)
+}
+
+func TestFetchLogsWithoutEvents(t *testing.T) {
+	client := fakeClient(map[string][]types.FilteredLogEvent{"a": {}})
+	sources := newLogSources([]string{"a"}, &cloudwatchlogs.FilterLogEventsInput{}, 1)
+	pages := 0
+	err := fetchLogs(context.Background(), client, sources, func(internal.Log) {}, func() { pages++ })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pages)
+	assert.True(t, sources[0].progress.Done)
+}
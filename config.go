@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// defaultConfigFile returns ~/.config/lc/config.yaml.
+func defaultConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "lc", "config.yaml")
+}
+
+// presetConflicts contains the settings of a preset which are dropped if a flag is
+// given on the command line, because they can't be used together or, for
+// start-time, describe another time range.
+var presetConflicts = map[string][]string{
+	starttime: {duration, endtime},
+	endtime:   {duration, follow},
+	duration:  {endtime},
+	follow:    {endtime},
+}
+
+// loadPreset reads the preset with the given name from the presets section of the
+// config file. The settings of the preset are merged as config values into viper,
+// so flags given on the command line still override them. A preset looks like:
+//
+//	presets:
+//	  gw-prod-errors:
+//	    log-group: /aws/containerinsights/eks-prod/application
+//	    filter-pattern: '{$.log=*error*}'
+//	    duration: 1h
+func loadPreset(configFile, name string) error {
+	cfg := viper.New()
+	cfg.SetConfigFile(configFile)
+	if err := cfg.ReadInConfig(); err != nil {
+		return err
+	}
+
+	preset := cfg.Sub("presets." + name)
+	if preset == nil {
+		return fmt.Errorf("preset %s not found in %s", name, configFile)
+	}
+
+	settings := preset.AllSettings()
+	for key := range settings {
+		if key == presetFlag || key == configFlag || flag.Lookup(key) == nil {
+			return fmt.Errorf("preset %s contains unknown setting %s", name, key)
+		}
+	}
+	for key, conflicts := range presetConflicts {
+		if !flag.Lookup(key).Changed {
+			continue
+		}
+		for _, conflict := range conflicts {
+			delete(settings, conflict)
+		}
+	}
+	return viper.MergeConfigMap(settings)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfig = `presets:
+  gw-prod-errors:
+    log-group: /aws/containerinsights/eks-prod/application
+    filter-pattern: '{$.log=*error*}'
+    logstream-prefix: gw-eks-int
+    filter-fields:
+      - log
+      - kubernetes.pod_name
+    output-format: yaml
+    duration: 1h
+  broken:
+    unknown-setting: true
+`
+
+func writeTestConfig(t *testing.T) string {
+	file := path.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(testConfig), 0644))
+	return file
+}
+
+func TestDefaultConfigFile(t *testing.T) {
+	assert.Contains(t, defaultConfigFile(), path.Join(".config", "lc", "config.yaml"))
+}
+
+func TestLoadPreset(t *testing.T) {
+	t.Cleanup(viper.Reset)
+
+	t.Run("preset values", func(t *testing.T) {
+		err := loadPreset(writeTestConfig(t), "gw-prod-errors")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"/aws/containerinsights/eks-prod/application"}, viper.GetStringSlice(loggroup))
+		assert.Equal(t, "{$.log=*error*}", viper.GetString(filter))
+		assert.Equal(t, "gw-eks-int", viper.GetString(logstreamprefix))
+		assert.Equal(t, []string{"log", "kubernetes.pod_name"}, viper.GetStringSlice(filterFields))
+		assert.Equal(t, "yaml", viper.GetString(outputFormat))
+		assert.Equal(t, "1h", viper.GetString(duration))
+		viper.Reset()
+	})
+	t.Run("flags override preset", func(t *testing.T) {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags.StringP(duration, "d", "", "")
+		flags.StringP(outputFormat, "t", "txt", "")
+		assert.NoError(t, viper.BindPFlags(flags))
+		assert.NoError(t, flags.Parse([]string{"-d", "2h"}))
+
+		err := loadPreset(writeTestConfig(t), "gw-prod-errors")
+		assert.NoError(t, err)
+		assert.Equal(t, "2h", viper.GetString(duration))
+		assert.Equal(t, "yaml", viper.GetString(outputFormat))
+		viper.Reset()
+	})
+	t.Run("flags replace conflicting preset values", func(t *testing.T) {
+		for name, value := range map[string]string{endtime: "now-1h", starttime: "today"} {
+			f := flag.Lookup(name)
+			assert.NoError(t, f.Value.Set(value))
+			f.Changed = true
+			assert.NoError(t, viper.BindPFlag(name, f))
+			err := loadPreset(writeTestConfig(t), "gw-prod-errors")
+			assert.NoError(t, err)
+			assert.Equal(t, value, viper.GetString(name))
+			assert.Empty(t, viper.GetString(duration))
+			viper.Set(loggroup, "testgroup")
+			assert.NoError(t, validateFlags())
+
+			assert.NoError(t, f.Value.Set(""))
+			f.Changed = false
+			viper.Reset()
+		}
+	})
+	t.Run("unknown preset", func(t *testing.T) {
+		file := writeTestConfig(t)
+		err := loadPreset(file, "missing")
+		assert.EqualError(t, err, "preset missing not found in "+file)
+		viper.Reset()
+	})
+	t.Run("unknown setting", func(t *testing.T) {
+		err := loadPreset(writeTestConfig(t), "broken")
+		assert.EqualError(t, err, "preset broken contains unknown setting unknown-setting")
+		viper.Reset()
+	})
+	t.Run("missing config file", func(t *testing.T) {
+		err := loadPreset(path.Join(t.TempDir(), "missing.yaml"), "gw-prod-errors")
+		assert.Error(t, err)
+		viper.Reset()
+	})
+}
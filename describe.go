@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+const (
+	groupsCommand  = "groups"
+	streamsCommand = "streams"
+
+	sortByName      = "name"
+	sortBySize      = "size"
+	sortByCreated   = "created"
+	sortByLastEvent = "last-event"
+)
+
+// listLogGroups returns all log groups starting with prefix.
+func listLogGroups(ctx context.Context, client cloudwatchlogs.DescribeLogGroupsAPIClient, prefix string) ([]types.LogGroup, error) {
+	input := &cloudwatchlogs.DescribeLogGroupsInput{}
+	if prefix != "" {
+		input.LogGroupNamePrefix = aws.String(prefix)
+	}
+
+	groups := []types.LogGroup{}
+	paginator := cloudwatchlogs.NewDescribeLogGroupsPaginator(client, input)
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, out.LogGroups...)
+	}
+	return groups, nil
+}
+
+// listLogStreams returns all log streams of group starting with prefix.
+func listLogStreams(ctx context.Context, client cloudwatchlogs.DescribeLogStreamsAPIClient, group, prefix string) ([]types.LogStream, error) {
+	input := &cloudwatchlogs.DescribeLogStreamsInput{LogGroupName: aws.String(group)}
+	if prefix != "" {
+		input.LogStreamNamePrefix = aws.String(prefix)
+	}
+
+	streams := []types.LogStream{}
+	paginator := cloudwatchlogs.NewDescribeLogStreamsPaginator(client, input)
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		streams = append(streams, out.LogStreams...)
+	}
+	return streams, nil
+}
+
+func sortLogGroups(groups []types.LogGroup, by string, reverse bool) error {
+	var less func(a, b types.LogGroup) bool
+	switch by {
+	case sortByName:
+		less = func(a, b types.LogGroup) bool { return aws.ToString(a.LogGroupName) < aws.ToString(b.LogGroupName) }
+	case sortBySize:
+		less = func(a, b types.LogGroup) bool { return aws.ToInt64(a.StoredBytes) < aws.ToInt64(b.StoredBytes) }
+	case sortByCreated:
+		less = func(a, b types.LogGroup) bool { return aws.ToInt64(a.CreationTime) < aws.ToInt64(b.CreationTime) }
+	default:
+		return fmt.Errorf("can't sort log groups by %s", by)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if reverse {
+			return less(groups[j], groups[i])
+		}
+		return less(groups[i], groups[j])
+	})
+	return nil
+}
+
+func sortLogStreams(streams []types.LogStream, by string, reverse bool) error {
+	var less func(a, b types.LogStream) bool
+	switch by {
+	case sortByName:
+		less = func(a, b types.LogStream) bool { return aws.ToString(a.LogStreamName) < aws.ToString(b.LogStreamName) }
+	case sortByCreated:
+		less = func(a, b types.LogStream) bool { return aws.ToInt64(a.CreationTime) < aws.ToInt64(b.CreationTime) }
+	case sortByLastEvent:
+		less = func(a, b types.LogStream) bool {
+			return aws.ToInt64(a.LastEventTimestamp) < aws.ToInt64(b.LastEventTimestamp)
+		}
+	default:
+		return fmt.Errorf("can't sort log streams by %s", by)
+	}
+	sort.SliceStable(streams, func(i, j int) bool {
+		if reverse {
+			return less(streams[j], streams[i])
+		}
+		return less(streams[i], streams[j])
+	})
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// describeStreamsClient returns one stream per page.
+type describeStreamsClient []string
+
+func (c describeStreamsClient) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	streams := []string{}
+	for _, name := range c {
+		if params.LogStreamNamePrefix == nil || strings.HasPrefix(name, *params.LogStreamNamePrefix) {
+			streams = append(streams, name)
+		}
+	}
+	idx := 0
+	if params.NextToken != nil {
+		for i, name := range streams {
+			if name == *params.NextToken {
+				idx = i
+			}
+		}
+	}
+	out := &cloudwatchlogs.DescribeLogStreamsOutput{}
+	if idx < len(streams) {
+		out.LogStreams = []types.LogStream{{LogStreamName: aws.String(streams[idx])}}
+	}
+	if idx+1 < len(streams) {
+		out.NextToken = aws.String(streams[idx+1])
+	}
+	return out, nil
+}
+
+func TestListLogGroups(t *testing.T) {
+	client := describeGroupsClient{"/aws/lambda/a", "/aws/lambda/b", "/aws/ecs/c"}
+
+	groups, err := listLogGroups(context.Background(), client, "/aws/lambda")
+	assert.NoError(t, err)
+	assert.Len(t, groups, 2)
+
+	groups, err = listLogGroups(context.Background(), client, "")
+	assert.NoError(t, err)
+	assert.Len(t, groups, 3)
+}
+
+func TestListLogStreams(t *testing.T) {
+	client := describeStreamsClient{"pod-a", "pod-b", "other"}
+
+	streams, err := listLogStreams(context.Background(), client, "group", "pod")
+	assert.NoError(t, err)
+	assert.Len(t, streams, 2)
+	assert.Equal(t, "pod-b", *streams[1].LogStreamName)
+}
+
+func TestSortLogGroups(t *testing.T) {
+	groups := []types.LogGroup{
+		{LogGroupName: aws.String("b"), StoredBytes: aws.Int64(1), CreationTime: aws.Int64(3)},
+		{LogGroupName: aws.String("a"), StoredBytes: aws.Int64(3), CreationTime: aws.Int64(2)},
+		{LogGroupName: aws.String("c"), StoredBytes: aws.Int64(2)},
+	}
+	names := func() string {
+		s := ""
+		for _, g := range groups {
+			s += *g.LogGroupName
+		}
+		return s
+	}
+
+	assert.NoError(t, sortLogGroups(groups, sortByName, false))
+	assert.Equal(t, "abc", names())
+	assert.NoError(t, sortLogGroups(groups, sortBySize, true))
+	assert.Equal(t, "acb", names())
+	assert.NoError(t, sortLogGroups(groups, sortByCreated, false))
+	assert.Equal(t, "cab", names())
+	assert.EqualError(t, sortLogGroups(groups, sortByLastEvent, false), "can't sort log groups by last-event")
+}
+
+func TestSortLogStreams(t *testing.T) {
+	streams := []types.LogStream{
+		{LogStreamName: aws.String("b"), LastEventTimestamp: aws.Int64(1), CreationTime: aws.Int64(3)},
+		{LogStreamName: aws.String("a"), LastEventTimestamp: aws.Int64(3), CreationTime: aws.Int64(2)},
+		{LogStreamName: aws.String("c"), LastEventTimestamp: aws.Int64(2)},
+	}
+	names := func() string {
+		s := ""
+		for _, stream := range streams {
+			s += *stream.LogStreamName
+		}
+		return s
+	}
+
+	assert.NoError(t, sortLogStreams(streams, sortByName, true))
+	assert.Equal(t, "cba", names())
+	assert.NoError(t, sortLogStreams(streams, sortByLastEvent, true))
+	assert.Equal(t, "acb", names())
+	assert.NoError(t, sortLogStreams(streams, sortByCreated, false))
+	assert.Equal(t, "cab", names())
+	assert.EqualError(t, sortLogStreams(streams, sortBySize, false), "can't sort log streams by size")
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/steffakasid/lc/internal"
+	"github.com/xhit/go-str2duration/v2"
+)
+
+// exportLogs fetches the logs of all groups, or the remaining logs of cp if an
+// export is resumed, and writes the events matching where to out. While writing to a file, the progress
+// is saved in the checkpoint file. In follow mode it returns once ctx is cancelled.
+func exportLogs(ctx context.Context, client cloudwatchlogs.FilterLogEventsAPIClient, groups []string, filterLogEvents *cloudwatchlogs.FilterLogEventsInput, cp *checkpoint, out logWriter) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	maxEventsLimit := &eventLimit{max: viper.GetInt(maxEvents), cancel: cancel}
+	defer func() {
+		if maxEventsLimit.truncated {
+			logger.Warnf("output was cut off after %d events (%s)", maxEventsLimit.count, maxEvents)
+		}
+	}()
+	handleEvent := maxEventsLimit.wrap(func(log internal.Log) {
+		out.write(log)
+	})
+	if viper.GetString(where) != "" {
+		condition, err := internal.NewCondition(viper.GetString(where))
+		if err != nil {
+			return err
+		}
+		// events are only counted for max-events if they match
+		handleMatching := handleEvent
+		handleEvent = func(log internal.Log) {
+			match, err := condition.Match(log)
+			CheckError(err, logger.Errorf)
+			if match {
+				handleMatching(log)
+			}
+		}
+	}
+
+	var sources []*logSource
+	var pageDone func()
+	if cp != nil {
+		sources = cp.logSources()
+		maxEventsLimit.count = cp.Written
+	} else {
+		sources = newLogSources(groups, filterLogEvents, viper.GetInt(parallel))
+	}
+	if out.resumable() {
+		if cp == nil {
+			cp = newCheckpoint(outputFile, currentOutputSettings(), sources)
+		}
+		pageDone = func() {
+			out.flush()
+			cp.Written = maxEventsLimit.count
+			CheckError(cp.save(viper.GetString(checkpointFile)), logger.Errorf)
+		}
+	}
+
+	err := fetchLogs(ctx, client, sources, handleEvent, pageDone)
+	out.flush()
+	if err != nil {
+		if pageDone != nil {
+			// save the progress of pages which were not completed
+			pageDone()
+			logger.Errorf("export stopped, continue it with --%s", resume)
+		}
+		return err
+	}
+	if pageDone != nil {
+		if ctx.Err() == nil || maxEventsLimit.truncated {
+			// the export is complete, so there is nothing to resume
+			if err := os.Remove(viper.GetString(checkpointFile)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		} else {
+			// interrupted, e.g. by Ctrl-C: save the events written from the current
+			// pages, so they are skipped when resuming
+			pageDone()
+			logger.Warnf("export interrupted, continue it with --%s", resume)
+			return nil
+		}
+	}
+
+	if viper.GetBool(follow) {
+		interval, err := str2duration.ParseDuration(viper.GetString(followInterval))
+		if err != nil {
+			return err
+		}
+		followLogs(ctx, client, sources, interval, func(log internal.Log) {
+			handleEvent(log)
+			out.flush()
+		})
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/spf13/viper"
+	"github.com/steffakasid/lc/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func exportTestEvents() map[string][]types.FilteredLogEvent {
+	events := map[string][]types.FilteredLogEvent{}
+	for i := 0; i < 20; i++ {
+		group := []string{"/aws/a", "/aws/b"}[i%2]
+		events[group] = append(events[group], types.FilteredLogEvent{
+			EventId:       aws.String(fmt.Sprint(i)),
+			Timestamp:     aws.Int64(int64(1000 + i)),
+			LogStreamName: aws.String("stream"),
+			Message:       aws.String(fmt.Sprintf("{\"log\": \"message %d\"}", i)),
+		})
+	}
+	return events
+}
+
+// setupExport configures viper to export jsonl into a temporary directory.
+func setupExport(t *testing.T) {
+	dir := t.TempDir()
+	outputFile = path.Join(dir, "logs.jsonl")
+	viper.Set(output, true)
+	viper.Set(outputFormat, "jsonl")
+	viper.Set(checkpointFile, path.Join(dir, "checkpoint.json"))
+	t.Cleanup(viper.Reset)
+}
+
+func exportTestInput() *cloudwatchlogs.FilterLogEventsInput {
+	return &cloudwatchlogs.FilterLogEventsInput{StartTime: aws.Int64(1000), EndTime: aws.Int64(2000)}
+}
+
+// exportedIds returns the event ids of all lines of the output file.
+func exportedIds(t *testing.T) []string {
+	bt, err := os.ReadFile(outputFile)
+	assert.NoError(t, err)
+	ids := []string{}
+	for _, line := range strings.Split(string(bt), "\n") {
+		if line == "" {
+			continue
+		}
+		log := &internal.YamlLog{}
+		assert.NoError(t, json.Unmarshal([]byte(line), log))
+		ids = append(ids, *log.EventId)
+	}
+	return ids
+}
+
+func export(t *testing.T, client *internal.FakeClient, cp *checkpoint) error {
+	out, err := openOutput(cp != nil && cp.Written > 0)
+	assert.NoError(t, err)
+	defer out.Close()
+	return exportLogs(context.Background(), client, []string{"/aws/a", "/aws/b"}, exportTestInput(), cp, out)
+}
+
+func TestExportLogs(t *testing.T) {
+	allIds := []string{}
+	for i := 0; i < 20; i++ {
+		allIds = append(allIds, fmt.Sprint(i))
+	}
+
+	t.Run("all events", func(t *testing.T) {
+		setupExport(t)
+		err := export(t, &internal.FakeClient{Events: exportTestEvents(), PageSize: 3}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, allIds, exportedIds(t))
+		assert.NoFileExists(t, viper.GetString(checkpointFile))
+	})
+
+	t.Run("parallel and throttled", func(t *testing.T) {
+		setupExport(t)
+		viper.Set(parallel, 3)
+		err := export(t, &internal.FakeClient{Events: exportTestEvents(), PageSize: 3, Throttles: 4}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, allIds, exportedIds(t))
+	})
+
+	t.Run("max events", func(t *testing.T) {
+		setupExport(t)
+		viper.Set(maxEvents, 5)
+		err := export(t, &internal.FakeClient{Events: exportTestEvents(), PageSize: 3}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, allIds[:5], exportedIds(t))
+		assert.NoFileExists(t, viper.GetString(checkpointFile))
+	})
+
+	t.Run("where", func(t *testing.T) {
+		setupExport(t)
+		viper.Set(where, `log =~ /message 1\d/ && metadata.timestamp < 1015`)
+		viper.Set(maxEvents, 3)
+		err := export(t, &internal.FakeClient{Events: exportTestEvents(), PageSize: 3}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10", "11", "12"}, exportedIds(t))
+	})
+
+	t.Run("split by stream", func(t *testing.T) {
+		setupExport(t)
+		viper.Set(splitBy, splitByStream)
+		events := exportTestEvents()
+		events["/aws/a"][0].LogStreamName = aws.String("other")
+		err := export(t, &internal.FakeClient{Events: events, PageSize: 3}, nil)
+		assert.NoError(t, err)
+
+		dir := strings.TrimSuffix(outputFile, ".jsonl")
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		outputFile = path.Join(dir, "stream.jsonl")
+		assert.Equal(t, allIds[1:], exportedIds(t))
+		outputFile = path.Join(dir, "other.jsonl")
+		assert.Equal(t, allIds[:1], exportedIds(t))
+	})
+
+	t.Run("compressed without checkpoint", func(t *testing.T) {
+		setupExport(t)
+		viper.Set(compress, compressGzip)
+		err := export(t, &internal.FakeClient{Events: exportTestEvents(), PageSize: 3, Err: internal.ErrFakeConnection, FailAfter: 4}, nil)
+		assert.ErrorIs(t, err, internal.ErrFakeConnection)
+		assert.NoFileExists(t, viper.GetString(checkpointFile))
+		// the stream is complete although the export stopped
+		decompress(t, compressGzip, outputFile)
+	})
+
+	t.Run("resume after interrupt", func(t *testing.T) {
+		setupExport(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		out, err := openOutput(false)
+		assert.NoError(t, err)
+		// interrupt in the middle of the second page
+		interrupting := &interruptingWriter{logWriter: out, after: 5, cancel: cancel}
+		err = exportLogs(ctx, &internal.FakeClient{Events: exportTestEvents(), PageSize: 3}, []string{"/aws/a", "/aws/b"}, exportTestInput(), nil, interrupting)
+		assert.NoError(t, err)
+		assert.NoError(t, out.Close())
+		written := exportedIds(t)
+		assert.Equal(t, allIds[:5], written)
+
+		cp, err := loadCheckpoint(viper.GetString(checkpointFile))
+		assert.NoError(t, err)
+		assert.Equal(t, 5, cp.Written)
+
+		err = export(t, &internal.FakeClient{Events: exportTestEvents(), PageSize: 3}, cp)
+		assert.NoError(t, err)
+		assert.Equal(t, allIds, exportedIds(t))
+		assert.NoFileExists(t, viper.GetString(checkpointFile))
+	})
+
+	t.Run("resume after error", func(t *testing.T) {
+		setupExport(t)
+		err := export(t, &internal.FakeClient{Events: exportTestEvents(), PageSize: 3, Err: internal.ErrFakeConnection, FailAfter: 4}, nil)
+		assert.ErrorIs(t, err, internal.ErrFakeConnection)
+		assert.FileExists(t, viper.GetString(checkpointFile))
+		written := exportedIds(t)
+		assert.Less(t, len(written), 20)
+
+		cp, err := loadCheckpoint(viper.GetString(checkpointFile))
+		assert.NoError(t, err)
+		assert.Equal(t, len(written), cp.Written)
+		assert.Equal(t, outputFile, cp.OutputFile)
+
+		err = export(t, &internal.FakeClient{Events: exportTestEvents(), PageSize: 3}, cp)
+		assert.NoError(t, err)
+		assert.Equal(t, allIds, exportedIds(t))
+		assert.NoFileExists(t, viper.GetString(checkpointFile))
+	})
+}
+
+// interruptingWriter cancels the export after writing the given number of events
+// like Ctrl-C does.
+type interruptingWriter struct {
+	logWriter
+	after  int
+	cancel context.CancelFunc
+}
+
+func (w *interruptingWriter) write(log internal.Printable) {
+	w.logWriter.write(log)
+	if w.after--; w.after == 0 {
+		w.cancel()
+	}
+}
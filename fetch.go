@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/smithy-go"
+	logger "github.com/sirupsen/logrus"
+	"github.com/steffakasid/lc/internal"
+)
+
+// logSource is one log group, or one time shard of a log group, to fetch events
+// from. The tracker is kept across follow mode polls.
+type logSource struct {
+	input   *cloudwatchlogs.FilterLogEventsInput
+	tracker *eventTracker
+	tag     bool
+	// last is true for the last time shard of a log group
+	last bool
+	// buffer is the number of events fetched in advance
+	buffer int
+	// bufferBytes limits the size of the messages fetched in advance if not 0
+	bufferBytes int
+	// skip contains the ids of events written before resuming
+	skip map[string]bool
+	// progress is updated with every handled event and saved in checkpoints
+	progress *sourceCheckpoint
+}
+
+const (
+	defaultBuffer = 100
+	// totalShardBuffer and totalShardBytes limit the events buffered by all time
+	// shards together. The buffers allow later time shards to be fetched while the
+	// events of earlier shards are still written. As messages can have up to 256 KB,
+	// the number of events alone doesn't limit the memory used.
+	totalShardBuffer = 100000
+	totalShardBytes  = 256 << 20
+)
+
+// setShardBuffer shares the buffers of all time shards between the n sources.
+// Each source buffers at least defaultBuffer events and one event of any size.
+func (s *logSource) setShardBuffer(n int) {
+	s.buffer = max(totalShardBuffer/max(n, 1), defaultBuffer)
+	s.bufferBytes = totalShardBytes / max(n, 1)
+}
+
+// byteBudget limits the size of the events a source fetched in advance to max
+// bytes. There is a single sender per budget, which can always send an event if
+// nothing is buffered, so large messages don't stop fetching.
+type byteBudget struct {
+	max      int
+	mu       sync.Mutex
+	used     int
+	released chan struct{}
+}
+
+// newByteBudget returns a budget of size bytes or nil, which doesn't limit
+// anything, if size is 0.
+func newByteBudget(size int) *byteBudget {
+	if size == 0 {
+		return nil
+	}
+	return &byteBudget{max: size, released: make(chan struct{}, 1)}
+}
+
+// acquire waits until n bytes are available. It returns false if ctx was
+// cancelled before.
+func (b *byteBudget) acquire(ctx context.Context, n int) bool {
+	if b == nil {
+		return true
+	}
+	for {
+		b.mu.Lock()
+		if b.used == 0 || b.used+n <= b.max {
+			b.used += n
+			b.mu.Unlock()
+			return true
+		}
+		b.mu.Unlock()
+		select {
+		case <-b.released:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// release returns n bytes to the budget after the event was handled.
+func (b *byteBudget) release(n int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	b.mu.Unlock()
+	select {
+	case b.released <- struct{}{}:
+	default:
+	}
+}
+
+// eventSize is the size of an event counted by byteBudgets.
+func eventSize(log *internal.Log) int {
+	return len(aws.ToString(log.Message))
+}
+
+var (
+	initialBackoff = 200 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+)
+
+// newLogSources returns one source per log group and time shard. The time range of
+// template is split into shards of the same length.
+func newLogSources(groups []string, template *cloudwatchlogs.FilterLogEventsInput, shards int) []*logSource {
+	windows := [][2]int64{{aws.ToInt64(template.StartTime), aws.ToInt64(template.EndTime)}}
+	if template.StartTime != nil && template.EndTime != nil {
+		windows = splitTimeRange(*template.StartTime, *template.EndTime, shards)
+	}
+
+	sources := []*logSource{}
+	for i := range groups {
+		for j, window := range windows {
+			input := *template
+			input.LogGroupName = &groups[i]
+			if len(windows) > 1 {
+				input.StartTime = aws.Int64(window[0])
+				input.EndTime = aws.Int64(window[1])
+			}
+			source := &logSource{
+				input:    &input,
+				tracker:  newEventTracker(),
+				tag:      len(groups) > 1,
+				last:     j == len(windows)-1,
+				buffer:   defaultBuffer,
+				progress: newSourceCheckpoint(&input),
+			}
+			if len(windows) > 1 {
+				source.setShardBuffer(len(groups) * len(windows))
+			}
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// splitTimeRange splits the range from start to end (both in milliseconds and
+// inclusive like the FilterLogEvents API) into n windows which don't overlap.
+func splitTimeRange(start, end int64, n int) [][2]int64 {
+	if n <= 1 || end-start+1 < int64(n) {
+		return [][2]int64{{start, end}}
+	}
+	windows := make([][2]int64, n)
+	size := (end - start + 1) / int64(n)
+	for i := range windows {
+		windows[i][0] = start + int64(i)*size
+		windows[i][1] = windows[i][0] + size - 1
+	}
+	windows[n-1][1] = end
+	return windows
+}
+
+// eventLimit stops fetching once max events were handled by cancelling the
+// context used to fetch them. A max of 0 means no limit.
+type eventLimit struct {
+	max       int
+	count     int
+	truncated bool
+	cancel    context.CancelFunc
+}
+
+// wrap returns a handler which calls handle for the first max events. The first
+// event above max marks the output as truncated and cancels fetching.
+func (l *eventLimit) wrap(handle func(internal.Log)) func(internal.Log) {
+	return func(log internal.Log) {
+		if l.max > 0 && l.count >= l.max {
+			l.truncated = true
+			l.cancel()
+			return
+		}
+		l.count++
+		handle(log)
+	}
+}
+
+// fetchedLog is an event of a source, the end of a page if log is nil or the error
+// which stopped fetching the source.
+type fetchedLog struct {
+	source *logSource
+	log    *internal.Log
+	// budget is released when the event was handled
+	budget *byteBudget
+	// token is the token the page was fetched with
+	token     *string
+	nextToken *string
+	err       error
+}
+
+// fetchLogs pages through all events of all sources at the same time and calls
+// handle for every event not seen before. Events of different sources are merged
+// by timestamp. pageDone is called, if not nil, after all events of a page were
+// handled. It returns when all pages are fetched or ctx is cancelled. If fetching
+// a source fails, all events fetched before are handled and the error is returned.
+func fetchLogs(ctx context.Context, client cloudwatchlogs.FilterLogEventsAPIClient, sources []*logSource, handle func(internal.Log), pageDone func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+	channels := make([]chan fetchedLog, len(sources))
+	for i, source := range sources {
+		channels[i] = make(chan fetchedLog, source.buffer)
+		wg.Add(1)
+		go func(source *logSource, fetched chan<- fetchedLog) {
+			defer wg.Done()
+			defer close(fetched)
+			if err := fetchSource(ctx, client, source, newByteBudget(source.bufferBytes), fetched); err != nil {
+				select {
+				case fetched <- fetchedLog{source: source, err: err}:
+				case <-ctx.Done():
+				}
+			}
+		}(source, channels[i])
+	}
+
+	err := mergeLogs(ctx, channels, func(fetched fetchedLog) {
+		if fetched.log == nil {
+			fetched.source.progress.pageDone(fetched.nextToken)
+			if pageDone != nil {
+				pageDone()
+			}
+			return
+		}
+		handle(*fetched.log)
+		fetched.budget.release(eventSize(fetched.log))
+		fetched.source.progress.written(fetched.token, aws.ToString(fetched.log.EventId))
+	})
+	cancel()
+	wg.Wait()
+	return err
+}
+
+// fetchSource sends all events of source to events, as long as budget allows it.
+// Throttled requests are retried with an exponential backoff, any other error stops
+// fetching.
+func fetchSource(ctx context.Context, client cloudwatchlogs.FilterLogEventsAPIClient, source *logSource, budget *byteBudget, events chan<- fetchedLog) error {
+	paginator := cloudwatchlogs.NewFilterLogEventsPaginator(client, source.input)
+	token := source.input.NextToken
+	backoff := time.Duration(0)
+
+	send := func(fetched fetchedLog) bool {
+		select {
+		case events <- fetched:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for paginator.HasMorePages() && ctx.Err() == nil {
+		logResults, err := paginator.NextPage(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if isThrottling(err) {
+			backoff = min(max(2*backoff, initialBackoff), maxBackoff)
+			logger.Debugf("throttled fetching %s, retrying in %s", aws.ToString(source.input.LogGroupName), backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil
+			}
+			continue
+		}
+		backoff = 0
+		if err != nil {
+			return fmt.Errorf("fetching %s: %w", aws.ToString(source.input.LogGroupName), err)
+		}
+
+		for _, event := range logResults.Events {
+			if source.tracker.seen(event) || source.skip[aws.ToString(event.EventId)] {
+				continue
+			}
+			log := internal.Log{FilteredLogEvent: event}
+			if source.tag {
+				log.LogGroupName = source.input.LogGroupName
+			}
+			if !budget.acquire(ctx, eventSize(&log)) || !send(fetchedLog{source: source, log: &log, budget: budget, token: token}) {
+				return nil
+			}
+		}
+		if !send(fetchedLog{source: source, token: token, nextToken: logResults.NextToken}) {
+			return nil
+		}
+		token = logResults.NextToken
+	}
+	return nil
+}
+
+func isThrottling(err error) bool {
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return false
+	}
+	_, ok := retry.DefaultThrottleErrorCodes[ae.ErrorCode()]
+	return ok
+}
+
+// mergeLogs always hands the oldest of the next events of all channels to handle
+// until all channels are closed. Page ends are handed over as soon as they are
+// the next item of a channel. The first error stops merging and is returned.
+func mergeLogs(ctx context.Context, channels []chan fetchedLog, handle func(fetchedLog)) error {
+	heads := make([]*fetchedLog, len(channels))
+	open := make([]bool, len(channels))
+	for i := range channels {
+		open[i] = true
+	}
+
+	for ctx.Err() == nil {
+		next := -1
+		for i, events := range channels {
+			if heads[i] == nil && open[i] {
+				fetched, ok := <-events
+				if ok {
+					heads[i] = &fetched
+				} else {
+					open[i] = false
+				}
+			}
+			if heads[i] != nil && (next < 0 || timestamp(*heads[i]) < timestamp(*heads[next])) {
+				next = i
+			}
+		}
+		if next < 0 {
+			return nil
+		}
+		if heads[next].err != nil {
+			return heads[next].err
+		}
+		handle(*heads[next])
+		heads[next] = nil
+	}
+	return nil
+}
+
+// timestamp returns the timestamp of the event or 0 for page ends and errors.
+func timestamp(fetched fetchedLog) int64 {
+	if fetched.log == nil || fetched.log.Timestamp == nil {
+		return 0
+	}
+	return *fetched.log.Timestamp
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	"github.com/steffakasid/lc/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClient returns a client replaying the events of each log group in pages of
+// two events.
+func fakeClient(events map[string][]types.FilteredLogEvent) *internal.FakeClient {
+	return &internal.FakeClient{Events: events, PageSize: 2}
+}
+
+func TestNewLogSources(t *testing.T) {
+	template := &cloudwatchlogs.FilterLogEventsInput{Limit: aws.Int32(10)}
+	sources := newLogSources([]string{"a", "b"}, template, 1)
+	assert.Len(t, sources, 2)
+	assert.Equal(t, "a", *sources[0].input.LogGroupName)
+	assert.Equal(t, "b", *sources[1].input.LogGroupName)
+	assert.Equal(t, int32(10), *sources[1].input.Limit)
+	assert.True(t, sources[0].tag)
+	assert.Nil(t, template.LogGroupName)
+
+	sources = newLogSources([]string{"a"}, template, 1)
+	assert.False(t, sources[0].tag)
+}
+
+func TestNewLogSourcesWithShards(t *testing.T) {
+	template := &cloudwatchlogs.FilterLogEventsInput{StartTime: aws.Int64(0), EndTime: aws.Int64(99)}
+	sources := newLogSources([]string{"a", "b"}, template, 4)
+	assert.Len(t, sources, 8)
+	assert.Equal(t, int64(0), *sources[0].input.StartTime)
+	assert.Equal(t, int64(24), *sources[0].input.EndTime)
+	assert.Equal(t, int64(75), *sources[3].input.StartTime)
+	assert.Equal(t, int64(99), *sources[3].input.EndTime)
+	assert.Equal(t, "b", *sources[4].input.LogGroupName)
+	assert.False(t, sources[2].last)
+	assert.True(t, sources[3].last)
+	// all shards of all groups share the buffer
+	assert.Equal(t, totalShardBuffer/8, sources[0].buffer)
+	assert.Equal(t, totalShardBytes/8, sources[0].bufferBytes)
+}
+
+func TestSetShardBuffer(t *testing.T) {
+	source := &logSource{}
+	source.setShardBuffer(4)
+	assert.Equal(t, totalShardBuffer/4, source.buffer)
+	assert.Equal(t, totalShardBytes/4, source.bufferBytes)
+	source.setShardBuffer(10000)
+	assert.Equal(t, defaultBuffer, source.buffer)
+	source.setShardBuffer(0)
+	assert.Equal(t, totalShardBuffer, source.buffer)
+}
+
+func TestByteBudget(t *testing.T) {
+	ctx := context.Background()
+	budget := newByteBudget(10)
+	// an event larger than the budget is accepted if nothing is buffered
+	assert.True(t, budget.acquire(ctx, 20))
+	acquired := make(chan bool)
+	go func() { acquired <- budget.acquire(ctx, 5) }()
+	select {
+	case <-acquired:
+		t.Fatal("acquired bytes above the budget")
+	case <-time.After(10 * time.Millisecond):
+	}
+	budget.release(20)
+	assert.True(t, <-acquired)
+	assert.True(t, budget.acquire(ctx, 5))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, budget.acquire(cancelled, 5))
+
+	var unlimited *byteBudget
+	assert.True(t, unlimited.acquire(ctx, 1<<30))
+	unlimited.release(1 << 30)
+	assert.Nil(t, newByteBudget(0))
+}
+
+func TestSplitTimeRange(t *testing.T) {
+	assert.Equal(t, [][2]int64{{0, 9}}, splitTimeRange(0, 9, 1))
+	assert.Equal(t, [][2]int64{{0, 2}, {3, 5}, {6, 9}}, splitTimeRange(0, 9, 3))
+	assert.Equal(t, [][2]int64{{5, 6}}, splitTimeRange(5, 6, 3))
+}
+
+func TestIsThrottling(t *testing.T) {
+	assert.True(t, isThrottling(&smithy.GenericAPIError{Code: "ThrottlingException"}))
+	assert.False(t, isThrottling(&smithy.GenericAPIError{Code: "ResourceNotFoundException"}))
+	assert.False(t, isThrottling(errors.New("error")))
+	assert.False(t, isThrottling(nil))
+}
+
+func TestFetchLogs(t *testing.T) {
+	t.Run("single group", func(t *testing.T) {
+		client := fakeClient(map[string][]types.FilteredLogEvent{
+			"a": {testEvent("1", 1), testEvent("2", 2), testEvent("3", 3)},
+		})
+		logs := []internal.Log{}
+		fetchLogs(context.Background(), client, newLogSources([]string{"a"}, &cloudwatchlogs.FilterLogEventsInput{}, 1), func(log internal.Log) {
+			logs = append(logs, log)
+		}, nil)
+		assert.Len(t, logs, 3)
+		assert.Nil(t, logs[0].LogGroupName)
+	})
+
+	t.Run("merge groups by timestamp", func(t *testing.T) {
+		client := fakeClient(map[string][]types.FilteredLogEvent{
+			"a": {testEvent("a1", 1), testEvent("a2", 4), testEvent("a3", 5)},
+			"b": {testEvent("b1", 2), testEvent("b2", 3), testEvent("b3", 6)},
+		})
+		ids := []string{}
+		groups := []string{}
+		fetchLogs(context.Background(), client, newLogSources([]string{"a", "b"}, &cloudwatchlogs.FilterLogEventsInput{}, 1), func(log internal.Log) {
+			ids = append(ids, *log.EventId)
+			groups = append(groups, *log.LogGroupName)
+		}, nil)
+		assert.Equal(t, []string{"a1", "b1", "b2", "a2", "a3", "b3"}, ids)
+		assert.Equal(t, []string{"a", "b", "b", "a", "a", "b"}, groups)
+	})
+
+	t.Run("parallel matches serial", func(t *testing.T) {
+		events := map[string][]types.FilteredLogEvent{}
+		for i := int64(0); i < 50; i++ {
+			group := []string{"a", "b"}[i%2]
+			events[group] = append(events[group], testEvent(fmt.Sprintf("%s%d", group, i), i*3))
+		}
+		client := fakeClient(events)
+		fetch := func(shards int) []string {
+			ids := []string{}
+			input := &cloudwatchlogs.FilterLogEventsInput{StartTime: aws.Int64(0), EndTime: aws.Int64(200)}
+			fetchLogs(context.Background(), client, newLogSources([]string{"a", "b"}, input, shards), func(log internal.Log) {
+				ids = append(ids, *log.EventId)
+			}, nil)
+			return ids
+		}
+		serial := fetch(1)
+		assert.Len(t, serial, 50)
+		assert.Equal(t, serial, fetch(4))
+		assert.Equal(t, serial, fetch(7))
+	})
+
+	t.Run("byte budget smaller than an event", func(t *testing.T) {
+		events := map[string][]types.FilteredLogEvent{}
+		for i := int64(0); i < 20; i++ {
+			events["a"] = append(events["a"], testEvent(fmt.Sprint(i), i))
+		}
+		sources := newLogSources([]string{"a"}, &cloudwatchlogs.FilterLogEventsInput{StartTime: aws.Int64(0), EndTime: aws.Int64(19)}, 4)
+		for _, source := range sources {
+			source.bufferBytes = 1
+		}
+		ids := []string{}
+		err := fetchLogs(context.Background(), fakeClient(events), sources, func(log internal.Log) {
+			ids = append(ids, *log.EventId)
+		}, nil)
+		assert.NoError(t, err)
+		assert.Len(t, ids, 20)
+		assert.Equal(t, "19", ids[19])
+	})
+
+	t.Run("retries throttled requests", func(t *testing.T) {
+		initialBackoff = time.Millisecond
+		client := fakeClient(map[string][]types.FilteredLogEvent{"a": {testEvent("1", 1), testEvent("2", 2), testEvent("3", 3)}})
+		client.Throttles = 2
+		ids := []string{}
+		fetchLogs(context.Background(), client, newLogSources([]string{"a"}, &cloudwatchlogs.FilterLogEventsInput{}, 1), func(log internal.Log) {
+			ids = append(ids, *log.EventId)
+		}, nil)
+		assert.Equal(t, []string{"1", "2", "3"}, ids)
+		assert.Equal(t, 4, client.Calls())
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		called := false
+		fetchLogs(ctx, fakeClient(nil), newLogSources([]string{"a"}, &cloudwatchlogs.FilterLogEventsInput{}, 1), func(internal.Log) { called = true }, nil)
+		assert.False(t, called)
+	})
+}
+
+func TestEventLimit(t *testing.T) {
+	client := fakeClient(map[string][]types.FilteredLogEvent{
+		"a": {testEvent("1", 1), testEvent("2", 2), testEvent("3", 3), testEvent("4", 4), testEvent("5", 5)},
+	})
+
+	t.Run("stops paging", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		limit := &eventLimit{max: 3, cancel: cancel}
+		ids := []string{}
+		fetchLogs(ctx, client, newLogSources([]string{"a"}, &cloudwatchlogs.FilterLogEventsInput{}, 1), limit.wrap(func(log internal.Log) {
+			ids = append(ids, *log.EventId)
+		}), nil)
+		assert.Equal(t, []string{"1", "2", "3"}, ids)
+		assert.True(t, limit.truncated)
+		assert.Error(t, ctx.Err())
+	})
+	t.Run("not truncated if all events fit", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		limit := &eventLimit{max: 5, cancel: cancel}
+		fetchLogs(ctx, client, newLogSources([]string{"a"}, &cloudwatchlogs.FilterLogEventsInput{}, 1), limit.wrap(func(internal.Log) {}), nil)
+		assert.Equal(t, 5, limit.count)
+		assert.False(t, limit.truncated)
+		assert.NoError(t, ctx.Err())
+	})
+	t.Run("no limit", func(t *testing.T) {
+		limit := &eventLimit{cancel: func() {}}
+		fetchLogs(context.Background(), client, newLogSources([]string{"a"}, &cloudwatchlogs.FilterLogEventsInput{}, 1), limit.wrap(func(internal.Log) {}), nil)
+		assert.Equal(t, 5, limit.count)
+		assert.False(t, limit.truncated)
+	})
+}
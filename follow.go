@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	logger "github.com/sirupsen/logrus"
+	"github.com/steffakasid/lc/internal"
+)
+
+// eventTracker remembers the newest timestamp seen and the ids of all events
+// with that timestamp, so polling again from that timestamp doesn't print
+// events twice.
+type eventTracker struct {
+	latest int64
+	ids    map[string]bool
+}
+
+func newEventTracker() *eventTracker {
+	return &eventTracker{ids: map[string]bool{}}
+}
+
+// seen returns true if the event was already handled and records it otherwise.
+func (t *eventTracker) seen(event types.FilteredLogEvent) bool {
+	if event.EventId == nil || event.Timestamp == nil {
+		return false
+	}
+	ts := *event.Timestamp
+	if ts < t.latest {
+		// polling starts at latest, so older events are never fetched twice
+		return false
+	}
+	if t.ids[*event.EventId] {
+		return true
+	}
+	if ts > t.latest {
+		t.latest = ts
+		t.ids = map[string]bool{}
+	}
+	t.ids[*event.EventId] = true
+	return false
+}
+
+// followLogs polls for new events every interval, starting at the newest timestamp
+// the tracker of the last time shard of each log group has seen, until ctx is
+// cancelled.
+func followLogs(ctx context.Context, client cloudwatchlogs.FilterLogEventsAPIClient, sources []*logSource, interval time.Duration, handle func(internal.Log)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pollSources := []*logSource{}
+			for _, source := range sources {
+				if !source.last {
+					continue
+				}
+				pollInput := *source.input
+				pollInput.NextToken = nil
+				pollInput.EndTime = nil
+				if source.tracker.latest > 0 {
+					pollInput.StartTime = aws.Int64(source.tracker.latest)
+				}
+				pollSources = append(pollSources, &logSource{input: &pollInput, tracker: source.tracker, tag: source.tag, last: true, buffer: defaultBuffer})
+			}
+			err := fetchLogs(ctx, client, pollSources, handle, nil)
+			CheckError(err, logger.Errorf)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/steffakasid/lc/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvent(id string, ts int64) types.FilteredLogEvent {
+	return types.FilteredLogEvent{
+		EventId:   aws.String(id),
+		Timestamp: aws.Int64(ts),
+		Message:   aws.String("{}"),
+	}
+}
+
+func testLog(id string, ts int64, stream, message string) internal.Log {
+	event := testEvent(id, ts)
+	event.LogStreamName = aws.String(stream)
+	event.Message = aws.String(message)
+	return internal.Log{FilteredLogEvent: event}
+}
+
+func TestEventTrackerSeen(t *testing.T) {
+	t.Run("new events", func(t *testing.T) {
+		tracker := newEventTracker()
+		assert.False(t, tracker.seen(testEvent("1", 100)))
+		assert.False(t, tracker.seen(testEvent("2", 100)))
+		assert.False(t, tracker.seen(testEvent("3", 200)))
+		assert.Equal(t, int64(200), tracker.latest)
+	})
+	t.Run("duplicates at latest timestamp", func(t *testing.T) {
+		tracker := newEventTracker()
+		assert.False(t, tracker.seen(testEvent("1", 100)))
+		assert.False(t, tracker.seen(testEvent("2", 100)))
+		assert.True(t, tracker.seen(testEvent("1", 100)))
+		assert.True(t, tracker.seen(testEvent("2", 100)))
+		assert.False(t, tracker.seen(testEvent("3", 100)))
+	})
+	t.Run("older events are not tracked", func(t *testing.T) {
+		tracker := newEventTracker()
+		assert.False(t, tracker.seen(testEvent("1", 200)))
+		assert.False(t, tracker.seen(testEvent("2", 100)))
+		assert.Equal(t, int64(200), tracker.latest)
+		assert.Len(t, tracker.ids, 1)
+	})
+	t.Run("event without id", func(t *testing.T) {
+		tracker := newEventTracker()
+		assert.False(t, tracker.seen(types.FilteredLogEvent{}))
+		assert.False(t, tracker.seen(types.FilteredLogEvent{}))
+	})
+}
+
+func TestFollowLogs(t *testing.T) {
+	client := &internal.FakeClient{Events: map[string][]types.FilteredLogEvent{
+		"group": {testEvent("1", 100), testEvent("2", 200)},
+	}}
+	mu := sync.Mutex{}
+	ids := []string{}
+	handle := func(log internal.Log) {
+		mu.Lock()
+		defer mu.Unlock()
+		ids = append(ids, *log.EventId)
+	}
+	handled := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, ids...)
+	}
+	// waitForPolls waits until the client got n more requests
+	waitForPolls := func(n int) {
+		calls := client.Calls()
+		assert.Eventually(t, func() bool { return client.Calls() >= calls+n }, time.Second, time.Millisecond)
+	}
+
+	sources := newLogSources([]string{"group"}, &cloudwatchlogs.FilterLogEventsInput{StartTime: aws.Int64(0), EndTime: aws.Int64(250)}, 1)
+	assert.NoError(t, fetchLogs(context.Background(), client, sources, handle, nil))
+	assert.Equal(t, []string{"1", "2"}, handled())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		followLogs(ctx, client, sources, time.Millisecond, handle)
+	}()
+
+	waitForPolls(2)
+	// same timestamp as the newest event seen and newer events
+	client.Append("group", testEvent("3", 200), testEvent("4", 300))
+	assert.Eventually(t, func() bool { return len(handled()) == 4 }, time.Second, time.Millisecond)
+	client.Append("group", testEvent("5", 300))
+	assert.Eventually(t, func() bool { return len(handled()) == 5 }, time.Second, time.Millisecond)
+	waitForPolls(3)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, handled())
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("followLogs didn't return after cancelling")
+	}
+}
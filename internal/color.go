@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+)
+
+// colorCodes are the ANSI SGR codes of the supported colors.
+var colorCodes = map[string]int{
+	"bold":    1,
+	"dim":     2,
+	"red":     31,
+	"green":   32,
+	"yellow":  33,
+	"blue":    34,
+	"magenta": 35,
+	"cyan":    36,
+	"white":   37,
+	"gray":    90,
+}
+
+// streamColors are used for log streams. Red and yellow are left out as they
+// highlight levels.
+var streamColors = []int{36, 32, 34, 35, 96, 92, 94, 95}
+
+// levelPatterns find the level of a JSON, logfmt or plain text message. The first
+// group is the level.
+var levelPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`"(?i:level|severity|lvl|loglevel)"\s*:\s*"(\w+)"`),
+	regexp.MustCompile(`\b(?i:level|severity|lvl)=(\w+)`),
+	regexp.MustCompile(`\b(FATAL|PANIC|CRITICAL|ERROR|WARNING|WARN|INFO|DEBUG|TRACE)\b`),
+}
+
+func colorize(code int, s string) string {
+	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", code, s)
+}
+
+// streamColor returns the same color for the same log stream.
+func streamColor(stream string) int {
+	h := fnv.New32a()
+	h.Write([]byte(stream))
+	return streamColors[h.Sum32()%uint32(len(streamColors))]
+}
+
+func levelColor(level string) (int, bool) {
+	switch strings.ToLower(level) {
+	case "fatal", "panic", "critical", "crit", "error", "err":
+		return colorCodes["red"], true
+	case "warning", "warn":
+		return colorCodes["yellow"], true
+	case "info":
+		return colorCodes["green"], true
+	case "debug", "trace":
+		return colorCodes["dim"], true
+	}
+	return 0, false
+}
+
+// highlightLevel colors the first level found in message.
+func highlightLevel(message string) string {
+	for _, pattern := range levelPatterns {
+		match := pattern.FindStringSubmatchIndex(message)
+		if match == nil {
+			continue
+		}
+		level := message[match[2]:match[3]]
+		if code, ok := levelColor(level); ok {
+			return message[:match[2]] + colorize(code, level) + message[match[3]:]
+		}
+	}
+	return message
+}
@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var _ Colorable = Log{}
+
+func TestStreamColor(t *testing.T) {
+	assert.Equal(t, streamColor("pod-a"), streamColor("pod-a"))
+	assert.Contains(t, streamColors, streamColor("pod-b"))
+}
+
+func TestHighlightLevel(t *testing.T) {
+	tests := map[string]string{
+		`{"level":"error","msg":"failed"}`:   "{\"level\":\"\x1b[31merror\x1b[0m\",\"msg\":\"failed\"}",
+		`{"Severity": "WARN"}`:               "{\"Severity\": \"\x1b[33mWARN\x1b[0m\"}",
+		`ts=1 level=info msg=started`:        "ts=1 level=\x1b[32minfo\x1b[0m msg=started",
+		`2022-01-02 ERROR connection closed`: "2022-01-02 \x1b[31mERROR\x1b[0m connection closed",
+		`[DEBUG] request done`:               "[\x1b[2mDEBUG\x1b[0m] request done",
+		`START RequestId: 1234`:              "START RequestId: 1234",
+		`{"level":"custom"} but INFO`:        "{\"level\":\"custom\"} but \x1b[32mINFO\x1b[0m",
+		`no errors here`:                     "no errors here",
+	}
+	for message, expected := range tests {
+		t.Run(message, func(t *testing.T) {
+			assert.Equal(t, expected, highlightLevel(message))
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// DefaultCsvColumns are used for logs if no columns are given to NewCsvWriter.
+var DefaultCsvColumns = []string{"metadata.timestamp", "metadata.log-stream-name", "metadata.event-id", "metadata.message"}
+
+type CsvWriter struct {
+	writer        *csv.Writer
+	columns       []string
+	headerWritten bool
+}
+
+// CsvRow is implemented by everything which can be written by a CsvWriter.
+type CsvRow interface {
+	// CsvColumns returns the columns to use if none are given to the CsvWriter.
+	CsvColumns() []string
+	// CsvValues returns the value of each column.
+	CsvValues(columns []string) ([]string, error)
+}
+
+// NewCsvWriter returns a writer which prints one row per CsvRow to w. Each column is
+// a dotted path into the log message or a metadata.<field> path. If no columns are
+// given the columns of the first row are used. Use ',' as comma for CSV and '\t'
+// for TSV.
+func NewCsvWriter(w io.Writer, comma rune, columns ...string) *CsvWriter {
+	if len(columns) == 0 {
+		columns = nil
+	}
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	return &CsvWriter{
+		writer:  writer,
+		columns: columns,
+	}
+}
+
+// SkipHeader prevents writing the header row, e.g. when appending to a file which
+// already contains it.
+func (c *CsvWriter) SkipHeader() {
+	c.headerWritten = true
+}
+
+func (c *CsvWriter) Write(r CsvRow) error {
+	if c.columns == nil {
+		c.columns = r.CsvColumns()
+	}
+	if !c.headerWritten {
+		if err := c.writer.Write(c.columns); err != nil {
+			return err
+		}
+		c.headerWritten = true
+	}
+
+	row, err := r.CsvValues(c.columns)
+	if err != nil {
+		return err
+	}
+	return c.writer.Write(row)
+}
+
+func (c *CsvWriter) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (l Log) CsvColumns() []string {
+	return DefaultCsvColumns
+}
+
+func (l Log) CsvValues(columns []string) ([]string, error) {
+	var message map[string]interface{}
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		if value, ok := l.metadataValue(column); ok {
+			row[i] = value
+			continue
+		}
+		if message == nil {
+			var err error
+			message, err = l.messageMap()
+			if err != nil {
+				return nil, err
+			}
+		}
+		row[i] = lookupField(message, column)
+	}
+	return row, nil
+}
+
+func (l Log) metadataValue(column string) (string, bool) {
+	if !strings.HasPrefix(strings.ToLower(column), "metadata.") {
+		return "", false
+	}
+	name := strings.ToLower(strings.SplitN(column, ".", 2)[1])
+	if !slices.Contains(metadataNames, name) {
+		return "", false
+	}
+	switch value := l.metadata()[name].(type) {
+	case string:
+		return value, true
+	case Millis:
+		return value.String(), true
+	}
+	return "", true
+}
+
+func lookupField(m map[string]interface{}, path string) string {
+	var value interface{} = m
+	for _, key := range strings.Split(path, ".") {
+		sub, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		if value, ok = sub[key]; !ok {
+			return ""
+		}
+	}
+
+	switch t := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case map[string]interface{}, []interface{}:
+		bt, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprint(t)
+		}
+		return string(bt)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func int64Value(i *int64) string {
+	if i == nil {
+		return ""
+	}
+	return fmt.Sprint(*i)
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCsvWriter(t *testing.T) {
+	t.Run("default columns", func(t *testing.T) {
+		log := setupLog()
+		buf := &bytes.Buffer{}
+		sut := NewCsvWriter(buf, ',')
+		assert.NoError(t, sut.Write(log))
+		assert.NoError(t, sut.Flush())
+
+		records, err := csv.NewReader(buf).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, records, 2)
+		assert.Equal(t, DefaultCsvColumns, records[0])
+		assert.Equal(t, []string{fmt.Sprint(*log.Timestamp), LOGSTREAMNAME, EVENTID, *log.Message}, records[1])
+	})
+
+	t.Run("selected columns", func(t *testing.T) {
+		log := setupLog()
+		buf := &bytes.Buffer{}
+		sut := NewCsvWriter(buf, ',', "metadata.Timestamp", "kubernetes.Pod_Name", "log", "kubernetes", "missing.path")
+		assert.NoError(t, sut.Write(log))
+		assert.NoError(t, sut.Write(log))
+		assert.NoError(t, sut.Flush())
+
+		records, err := csv.NewReader(buf).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, records, 3)
+		assert.Equal(t, []string{"metadata.Timestamp", "kubernetes.Pod_Name", "log", "kubernetes", "missing.path"}, records[0])
+		assert.Equal(t, []string{fmt.Sprint(*log.Timestamp), "xyz", "something", `{"Pod_Name":"xyz","namespace":"something"}`, ""}, records[1])
+	})
+
+	t.Run("tsv escapes values", func(t *testing.T) {
+		log := setupLog()
+		log.Message = aws.String("{ \"log\": \"tab\\there \\\"quoted\\\"\\nnewline\" }")
+		buf := &bytes.Buffer{}
+		sut := NewCsvWriter(buf, '\t', "log")
+		assert.NoError(t, sut.Write(log))
+		assert.NoError(t, sut.Flush())
+
+		reader := csv.NewReader(buf)
+		reader.Comma = '\t'
+		records, err := reader.ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, "tab\there \"quoted\"\nnewline", records[1][0])
+	})
+
+	t.Run("skip header", func(t *testing.T) {
+		log := setupLog()
+		buf := &bytes.Buffer{}
+		sut := NewCsvWriter(buf, ',', "log")
+		sut.SkipHeader()
+		assert.NoError(t, sut.Write(log))
+		assert.NoError(t, sut.Flush())
+		assert.Equal(t, "something\n", buf.String())
+	})
+
+	t.Run("plain text message", func(t *testing.T) {
+		log := setupLog()
+		log.Message = aws.String("no json")
+		buf := &bytes.Buffer{}
+		sut := NewCsvWriter(buf, ',', "log", TextField)
+		assert.NoError(t, sut.Write(log))
+		assert.NoError(t, sut.Flush())
+
+		records, err := csv.NewReader(buf).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"", "no json"}, records[1])
+	})
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// LogGroupRecord returns the fields of a log group which are printed by lc groups.
+func LogGroupRecord(group types.LogGroup) Record {
+	retention := "never expire"
+	if group.RetentionInDays != nil {
+		retention = fmt.Sprintf("%d days", *group.RetentionInDays)
+	}
+	return Record{
+		field("name", stringValue(group.LogGroupName)),
+		field("stored-bytes", int64Value(group.StoredBytes)),
+		field("retention", retention),
+		field("creation-time", timeValue(group.CreationTime)),
+	}
+}
+
+// LogStreamRecord returns the fields of a log stream which are printed by lc streams.
+// The log group is only added if group is not nil.
+func LogStreamRecord(stream types.LogStream, group *string) Record {
+	record := Record{}
+	if group != nil {
+		record = append(record, field("log-group-name", *group))
+	}
+	return append(record,
+		field("name", stringValue(stream.LogStreamName)),
+		field("creation-time", timeValue(stream.CreationTime)),
+		field("first-event-time", timeValue(stream.FirstEventTimestamp)),
+		field("last-event-time", timeValue(stream.LastEventTimestamp)),
+		field("last-ingestion-time", timeValue(stream.LastIngestionTime)),
+	)
+}
+
+func field(name, value string) types.ResultField {
+	return types.ResultField{Field: aws.String(name), Value: aws.String(value)}
+}
+
+// timeValue returns a timestamp formatted like in txt output.
+func timeValue(i *int64) string {
+	if i == nil {
+		return ""
+	}
+	return formatMillis(*i)
+}
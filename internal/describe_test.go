@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogGroupRecord(t *testing.T) {
+	created := time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	t.Run("with retention", func(t *testing.T) {
+		record := LogGroupRecord(types.LogGroup{
+			LogGroupName:    aws.String("group"),
+			StoredBytes:     aws.Int64(1024),
+			RetentionInDays: aws.Int32(30),
+			CreationTime:    aws.Int64(created.UnixMilli()),
+		})
+		assert.Equal(t, map[string]interface{}{
+			"name":          "group",
+			"stored-bytes":  "1024",
+			"retention":     "30 days",
+			"creation-time": created.Local().Format(time.RFC3339),
+		}, record.toMap())
+	})
+	t.Run("without retention", func(t *testing.T) {
+		record := LogGroupRecord(types.LogGroup{LogGroupName: aws.String("group")})
+		assert.Equal(t, "never expire", record.toMap()["retention"])
+		assert.Equal(t, "", record.toMap()["creation-time"])
+	})
+}
+
+func TestLogStreamRecord(t *testing.T) {
+	lastEvent := time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC)
+	stream := types.LogStream{
+		LogStreamName:      aws.String("stream"),
+		LastEventTimestamp: aws.Int64(lastEvent.UnixMilli()),
+	}
+
+	t.Run("without group", func(t *testing.T) {
+		record := LogStreamRecord(stream, nil)
+		assert.Equal(t, []string{"name", "creation-time", "first-event-time", "last-event-time", "last-ingestion-time"}, record.CsvColumns())
+		assert.Equal(t, lastEvent.Local().Format(time.RFC3339), record.toMap()["last-event-time"])
+	})
+	t.Run("with group", func(t *testing.T) {
+		record := LogStreamRecord(stream, aws.String("group"))
+		assert.Equal(t, "group", record.toMap()["log-group-name"])
+	})
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+)
+
+// ErrFakeConnection can be used as FakeClient.Err to simulate a network error.
+var ErrFakeConnection = errors.New("connection reset by peer")
+
+// FakeClient is an in-memory replacement for the FilterLogEvents API of the
+// CloudWatch Logs client. It replays the fixture events of each log group in the
+// given order and supports paging, time and log stream filters and simulated
+// throttling and errors. Filter patterns are matched as plain substrings.
+type FakeClient struct {
+	// Events contains the fixture events per log group.
+	Events map[string][]types.FilteredLogEvent
+	// PageSize is the maximum number of events per page, 0 returns all events at once.
+	PageSize int
+	// Throttles is the number of requests which fail with a ThrottlingException
+	// before any request succeeds.
+	Throttles int
+	// Err is returned by all requests after FailAfter successful requests.
+	Err       error
+	FailAfter int
+
+	mu        sync.Mutex
+	calls     int
+	successes int
+}
+
+// Calls returns the number of FilterLogEvents requests including failed ones.
+func (f *FakeClient) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// Append adds events to the log group while the client is used, e.g. to simulate
+// new events in follow mode.
+func (f *FakeClient) Append(group string, events ...types.FilteredLogEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Events[group] = append(f.Events[group], events...)
+}
+
+func (f *FakeClient) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.calls <= f.Throttles {
+		return nil, &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded", Fault: smithy.FaultClient}
+	}
+	if f.Err != nil && f.successes >= f.FailAfter {
+		return nil, f.Err
+	}
+
+	all, ok := f.Events[aws.ToString(params.LogGroupName)]
+	if !ok {
+		return nil, &smithy.GenericAPIError{Code: "ResourceNotFoundException", Message: "The specified log group does not exist.", Fault: smithy.FaultClient}
+	}
+
+	events := []types.FilteredLogEvent{}
+	for _, event := range all {
+		if matches(params, event) {
+			events = append(events, event)
+		}
+	}
+
+	start := 0
+	if params.NextToken != nil {
+		var err error
+		start, err = strconv.Atoi(*params.NextToken)
+		if err != nil || start > len(events) {
+			return nil, &smithy.GenericAPIError{Code: "InvalidParameterException", Message: "The specified nextToken is invalid.", Fault: smithy.FaultClient}
+		}
+	}
+	size := f.PageSize
+	if params.Limit != nil && (size == 0 || int(*params.Limit) < size) {
+		size = int(*params.Limit)
+	}
+	end := len(events)
+	if size > 0 && start+size < end {
+		end = start + size
+	}
+
+	out := &cloudwatchlogs.FilterLogEventsOutput{Events: events[start:end]}
+	if end < len(events) {
+		out.NextToken = aws.String(strconv.Itoa(end))
+	}
+	f.successes++
+	return out, nil
+}
+
+func matches(params *cloudwatchlogs.FilterLogEventsInput, event types.FilteredLogEvent) bool {
+	ts := aws.ToInt64(event.Timestamp)
+	stream := aws.ToString(event.LogStreamName)
+	if params.StartTime != nil && ts < *params.StartTime {
+		return false
+	}
+	if params.EndTime != nil && ts > *params.EndTime {
+		return false
+	}
+	if params.LogStreamNamePrefix != nil && !strings.HasPrefix(stream, *params.LogStreamNamePrefix) {
+		return false
+	}
+	if len(params.LogStreamNames) > 0 {
+		found := false
+		for _, name := range params.LogStreamNames {
+			found = found || name == stream
+		}
+		if !found {
+			return false
+		}
+	}
+	if params.FilterPattern != nil && !strings.Contains(aws.ToString(event.Message), *params.FilterPattern) {
+		return false
+	}
+	return true
+}
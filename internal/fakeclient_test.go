@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ cloudwatchlogs.FilterLogEventsAPIClient = &FakeClient{}
+
+func fakeEvents(n int) []types.FilteredLogEvent {
+	events := []types.FilteredLogEvent{}
+	for i := 0; i < n; i++ {
+		events = append(events, types.FilteredLogEvent{
+			EventId:       aws.String(fmt.Sprint(i)),
+			Timestamp:     aws.Int64(int64(i * 10)),
+			LogStreamName: aws.String(fmt.Sprintf("stream-%d", i%2)),
+			Message:       aws.String(fmt.Sprintf("{\"log\": \"message %d\"}", i)),
+		})
+	}
+	return events
+}
+
+func TestFakeClientPaging(t *testing.T) {
+	client := &FakeClient{Events: map[string][]types.FilteredLogEvent{"group": fakeEvents(5)}, PageSize: 2}
+	paginator := cloudwatchlogs.NewFilterLogEventsPaginator(client, &cloudwatchlogs.FilterLogEventsInput{LogGroupName: aws.String("group")})
+
+	pages := 0
+	ids := []string{}
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(context.Background())
+		assert.NoError(t, err)
+		pages++
+		for _, event := range out.Events {
+			ids = append(ids, *event.EventId)
+		}
+	}
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, ids)
+	assert.Equal(t, 3, client.Calls())
+}
+
+func TestFakeClientFilters(t *testing.T) {
+	client := &FakeClient{Events: map[string][]types.FilteredLogEvent{"group": fakeEvents(10)}}
+	filter := func(input *cloudwatchlogs.FilterLogEventsInput) []string {
+		input.LogGroupName = aws.String("group")
+		out, err := client.FilterLogEvents(context.Background(), input)
+		assert.NoError(t, err)
+		ids := []string{}
+		for _, event := range out.Events {
+			ids = append(ids, *event.EventId)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"2", "3", "4"}, filter(&cloudwatchlogs.FilterLogEventsInput{StartTime: aws.Int64(20), EndTime: aws.Int64(40)}))
+	assert.Equal(t, []string{"1", "3", "5", "7", "9"}, filter(&cloudwatchlogs.FilterLogEventsInput{LogStreamNamePrefix: aws.String("stream-1")}))
+	assert.Equal(t, []string{"0", "2"}, filter(&cloudwatchlogs.FilterLogEventsInput{LogStreamNames: []string{"stream-0"}, Limit: aws.Int32(2)}))
+	assert.Equal(t, []string{"7"}, filter(&cloudwatchlogs.FilterLogEventsInput{FilterPattern: aws.String("message 7")}))
+}
+
+func TestFakeClientErrors(t *testing.T) {
+	t.Run("throttling", func(t *testing.T) {
+		client := &FakeClient{Events: map[string][]types.FilteredLogEvent{"group": fakeEvents(1)}, Throttles: 1}
+		_, err := client.FilterLogEvents(context.Background(), &cloudwatchlogs.FilterLogEventsInput{LogGroupName: aws.String("group")})
+		var ae smithy.APIError
+		assert.ErrorAs(t, err, &ae)
+		assert.Equal(t, "ThrottlingException", ae.ErrorCode())
+		_, err = client.FilterLogEvents(context.Background(), &cloudwatchlogs.FilterLogEventsInput{LogGroupName: aws.String("group")})
+		assert.NoError(t, err)
+	})
+	t.Run("fail after", func(t *testing.T) {
+		client := &FakeClient{Events: map[string][]types.FilteredLogEvent{"group": fakeEvents(1)}, Err: ErrFakeConnection, FailAfter: 1}
+		_, err := client.FilterLogEvents(context.Background(), &cloudwatchlogs.FilterLogEventsInput{LogGroupName: aws.String("group")})
+		assert.NoError(t, err)
+		_, err = client.FilterLogEvents(context.Background(), &cloudwatchlogs.FilterLogEventsInput{LogGroupName: aws.String("group")})
+		assert.ErrorIs(t, err, ErrFakeConnection)
+	})
+	t.Run("unknown group", func(t *testing.T) {
+		client := &FakeClient{}
+		_, err := client.FilterLogEvents(context.Background(), &cloudwatchlogs.FilterLogEventsInput{LogGroupName: aws.String("missing")})
+		var ae smithy.APIError
+		assert.ErrorAs(t, err, &ae)
+		assert.Equal(t, "ResourceNotFoundException", ae.ErrorCode())
+	})
+	t.Run("invalid token", func(t *testing.T) {
+		client := &FakeClient{Events: map[string][]types.FilteredLogEvent{"group": fakeEvents(1)}}
+		_, err := client.FilterLogEvents(context.Background(), &cloudwatchlogs.FilterLogEventsInput{LogGroupName: aws.String("group"), NextToken: aws.String("x")})
+		assert.Error(t, err)
+	})
+}
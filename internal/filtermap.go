@@ -1,21 +1,181 @@
 package internal
 
+import (
+	"strconv"
+	"strings"
+)
+
+// segment is a key of a map or, if index is true, the position in an array.
+type segment struct {
+	key   string
+	index bool
+}
+
+// pathNode is a segment of the paths given to fieldFilter. Paths with a common
+// parent share the nodes of the parent.
+type pathNode struct {
+	children map[string]*pathNode
+	// include and exclude are set if a path ends at this node
+	include, exclude bool
+	// recursive is set for ** which matches any number of keys
+	recursive bool
+}
+
+func (n *pathNode) child(key string) *pathNode {
+	if n.children == nil {
+		n.children = map[string]*pathNode{}
+	}
+	c, ok := n.children[key]
+	if !ok {
+		c = &pathNode{recursive: key == "**"}
+		n.children[key] = c
+	}
+	return c
+}
+
+// fieldFilter selects fields by dotted paths like kubernetes.pod_name. Paths
+// starting with ! are excluded. * matches any key or array element, ** any number
+// of keys. Paths don't need to contain array indexes, e.g. containers.name selects
+// the name of all elements of the array containers.
+//
+// If paths overlap, the most specific path decides whether a field is kept, e.g.
+// kubernetes and !kubernetes.labels keeps kubernetes without labels. If an include
+// and an exclude end at the same field, it's excluded.
+type fieldFilter struct {
+	root *pathNode
+	// includes is set if any path is included. Otherwise all fields which are not
+	// excluded are kept.
+	includes bool
+}
+
+func newFieldFilter(filter ...string) fieldFilter {
+	f := fieldFilter{root: &pathNode{}}
+	for _, entry := range filter {
+		path, exclude := strings.CutPrefix(entry, "!")
+		node := f.root
+		for _, key := range strings.Split(path, ".") {
+			node = node.child(key)
+		}
+		if exclude {
+			node.exclude = true
+		} else {
+			node.include = true
+			f.includes = true
+		}
+	}
+	return f
+}
+
 func filterMap(m map[string]interface{}, filter ...string) {
-	for key := range m {
-		contained, subfilter := contains(filter, key)
-		if contained {
-			switch t := m[key].(type) {
-			case map[string]interface{}:
-				if len(subfilter) > 0 {
-					filterMap(t, subfilter)
-				}
-			default:
-				// can"t apply subfilter
+	f := newFieldFilter(filter...)
+	f.filterMap(f.start(), m)
+}
+
+// match is the state of walking a path through the tree of a fieldFilter.
+type match struct {
+	// nodes are all nodes which match the path so far
+	nodes []*pathNode
+	// keep is the decision of the most specific path matching so far
+	keep bool
+}
+
+func (f fieldFilter) start() match {
+	return match{nodes: expand([]*pathNode{f.root}), keep: !f.includes}
+}
+
+// next returns the match of the path extended by s.
+func (m match) next(s segment) match {
+	nodes := []*pathNode{}
+	for _, node := range m.nodes {
+		if node.recursive || s.index {
+			nodes = append(nodes, node)
+		}
+		for _, key := range []string{s.key, "*"} {
+			if c, ok := node.children[key]; ok {
+				nodes = append(nodes, c)
 			}
 		}
+	}
+	next := match{nodes: expand(nodes), keep: m.keep}
+	include, exclude := false, false
+	for _, node := range next.nodes {
+		include = include || node.include
+		exclude = exclude || node.exclude
+	}
+	if include || exclude {
+		next.keep = !exclude
+	}
+	return next
+}
+
+// final returns true if no path goes beyond the current one, so the whole value
+// is kept or dropped.
+func (m match) final() bool {
+	for _, node := range m.nodes {
+		if len(node.children) > 0 || node.recursive {
+			return false
+		}
+	}
+	return true
+}
+
+// expand adds the ** children of nodes as they also match no key at all and
+// removes duplicates.
+func expand(nodes []*pathNode) []*pathNode {
+	seen := map[*pathNode]bool{}
+	expanded := []*pathNode{}
+	for i := 0; i < len(nodes); i++ {
+		if seen[nodes[i]] {
+			continue
+		}
+		seen[nodes[i]] = true
+		expanded = append(expanded, nodes[i])
+		if c, ok := nodes[i].children["**"]; ok {
+			nodes = append(nodes, c)
+		}
+	}
+	return expanded
+}
 
-		if !contained {
-			delete(m, key)
+func (f fieldFilter) filterMap(m match, value map[string]interface{}) {
+	for key, child := range value {
+		if filtered, keep := f.filterValue(m.next(segment{key: key}), child); keep {
+			value[key] = filtered
+		} else {
+			delete(value, key)
 		}
 	}
 }
+
+// filterValue returns the value without the fields which are not selected and
+// whether the value is kept at all. Maps and arrays which are only kept because
+// selected fields could be inside them are dropped if nothing was selected.
+func (f fieldFilter) filterValue(m match, value interface{}) (interface{}, bool) {
+	if m.final() {
+		return value, m.keep
+	}
+	switch t := value.(type) {
+	case map[string]interface{}:
+		f.filterMap(m, t)
+		return t, m.keep || len(t) > 0
+	case []interface{}:
+		kept := []interface{}{}
+		for i, child := range t {
+			if filtered, keep := f.filterValue(m.next(segment{key: strconv.Itoa(i), index: true}), child); keep {
+				kept = append(kept, filtered)
+			}
+		}
+		return kept, m.keep || len(kept) > 0
+	}
+	return value, m.keep
+}
+
+// keeps returns true if the value at path is kept. Fields inside it may still be
+// excluded.
+func (f fieldFilter) keeps(path ...string) bool {
+	m := f.start()
+	for _, key := range path {
+		m = m.next(segment{key: key})
+	}
+	return m.keep
+}
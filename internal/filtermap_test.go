@@ -40,4 +40,206 @@ func TestFilterMap(t *testing.T) {
 		assert.Contains(t, sut["key3"].(map[string]interface{})["subkey2"], "subsubkey2")
 		assert.NotContains(t, sut, "key4")
 	})
+
+	t.Run("exclude", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "!kubernetes.labels", "!kubernetes.annotations")
+		assert.Equal(t, map[string]interface{}{
+			"log":    "request failed",
+			"stream": "stderr",
+			"kubernetes": map[string]interface{}{
+				"pod_name": "gw-1",
+				"containers": []interface{}{
+					map[string]interface{}{"name": "gw", "image": "gw:1.0"},
+					map[string]interface{}{"name": "proxy", "image": "envoy:1.2"},
+				},
+			},
+			"trace": map[string]interface{}{"trace_id": "abc", "span_id": "def"},
+		}, sut)
+	})
+	t.Run("include and exclude", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "kubernetes", "!kubernetes.labels", "!kubernetes.annotations", "!kubernetes.containers")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{"pod_name": "gw-1"},
+		}, sut)
+	})
+	t.Run("wildcard", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "kubernetes.*.app")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"labels":      map[string]interface{}{"app": "gw"},
+				"annotations": map[string]interface{}{"app": "annotated"},
+			},
+		}, sut)
+	})
+	t.Run("double wildcard", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "**.trace_id", "log")
+		assert.Equal(t, map[string]interface{}{
+			"log":   "request failed",
+			"trace": map[string]interface{}{"trace_id": "abc"},
+		}, sut)
+
+		sut = containerInsightsMessage()
+		filterMap(sut, "!**.image", "!**.labels", "!**.annotations", "!trace", "!log", "!stream")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"pod_name": "gw-1",
+				"containers": []interface{}{
+					map[string]interface{}{"name": "gw"},
+					map[string]interface{}{"name": "proxy"},
+				},
+			},
+		}, sut)
+	})
+	t.Run("arrays", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "kubernetes.containers.name")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "gw"},
+					map[string]interface{}{"name": "proxy"},
+				},
+			},
+		}, sut)
+
+		sut = containerInsightsMessage()
+		filterMap(sut, "kubernetes.containers.1.image")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"image": "envoy:1.2"},
+				},
+			},
+		}, sut)
+
+		sut = containerInsightsMessage()
+		filterMap(sut, "kubernetes.containers.*.image")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"image": "gw:1.0"},
+					map[string]interface{}{"image": "envoy:1.2"},
+				},
+			},
+		}, sut)
+
+		sut = map[string]interface{}{"tags": []interface{}{"a", "b"}}
+		filterMap(sut, "tags.0")
+		assert.Equal(t, map[string]interface{}{"tags": []interface{}{"a"}}, sut)
+	})
+	t.Run("missing fields", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "kubernetes.missing", "log.sub")
+		assert.Empty(t, sut)
+	})
+}
+
+func TestFilterMapOverlappingPaths(t *testing.T) {
+	t.Run("same parent", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		sut["kubernetes"].(map[string]interface{})["namespace_name"] = "gw"
+		filterMap(sut, "kubernetes.pod_name", "kubernetes.namespace_name", "kubernetes.labels.app")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"pod_name":       "gw-1",
+				"namespace_name": "gw",
+				"labels":         map[string]interface{}{"app": "gw"},
+			},
+		}, sut)
+	})
+	t.Run("parent and child", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "trace.trace_id", "trace")
+		assert.Equal(t, map[string]interface{}{
+			"trace": map[string]interface{}{"trace_id": "abc", "span_id": "def"},
+		}, sut)
+	})
+	t.Run("wildcard and key", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "kubernetes.*.app", "kubernetes.labels.pod-template-hash")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"labels":      map[string]interface{}{"app": "gw", "pod-template-hash": "123"},
+				"annotations": map[string]interface{}{"app": "annotated"},
+			},
+		}, sut)
+	})
+	t.Run("exclude inside include", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "trace", "!trace.span_id")
+		assert.Equal(t, map[string]interface{}{
+			"trace": map[string]interface{}{"trace_id": "abc"},
+		}, sut)
+	})
+	t.Run("include inside exclude", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "!kubernetes", "kubernetes.pod_name")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{"pod_name": "gw-1"},
+		}, sut)
+
+		sut = containerInsightsMessage()
+		filterMap(sut, "!kubernetes", "!trace", "kubernetes.pod_name")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{"pod_name": "gw-1"},
+		}, sut)
+	})
+	t.Run("include and exclude of the same path", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "trace", "log", "!trace")
+		assert.Equal(t, map[string]interface{}{"log": "request failed"}, sut)
+
+		sut = containerInsightsMessage()
+		filterMap(sut, "kubernetes.*", "!kubernetes.labels", "!kubernetes.annotations", "!kubernetes.containers")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{"pod_name": "gw-1"},
+		}, sut)
+	})
+	t.Run("exclude more specific wildcard", func(t *testing.T) {
+		sut := containerInsightsMessage()
+		filterMap(sut, "kubernetes.containers", "!kubernetes.containers.1")
+		assert.Equal(t, map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "gw", "image": "gw:1.0"},
+				},
+			},
+		}, sut)
+	})
+}
+
+func TestFieldFilterKeeps(t *testing.T) {
+	f := newFieldFilter("metadata", "!metadata.event-id", "log")
+	assert.True(t, f.keeps("metadata", "timestamp"))
+	assert.False(t, f.keeps("metadata", "event-id"))
+	assert.False(t, f.keeps("kubernetes"))
+
+	f = newFieldFilter("!metadata", "metadata.timestamp")
+	assert.True(t, f.keeps("metadata", "timestamp"))
+	assert.False(t, f.keeps("metadata", "event-id"))
+	assert.False(t, f.keeps("log"))
+
+	f = newFieldFilter()
+	assert.True(t, f.keeps("metadata", "event-id"))
+}
+
+func containerInsightsMessage() map[string]interface{} {
+	return map[string]interface{}{
+		"log":    "request failed",
+		"stream": "stderr",
+		"kubernetes": map[string]interface{}{
+			"pod_name":    "gw-1",
+			"labels":      map[string]interface{}{"app": "gw", "pod-template-hash": "123"},
+			"annotations": map[string]interface{}{"app": "annotated", "checksum/config": "456"},
+			"containers": []interface{}{
+				map[string]interface{}{"name": "gw", "image": "gw:1.0"},
+				map[string]interface{}{"name": "proxy", "image": "envoy:1.2"},
+			},
+		},
+		"trace": map[string]interface{}{"trace_id": "abc", "span_id": "def"},
+	}
 }
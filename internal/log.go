@@ -1,24 +1,29 @@
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
-	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 )
 
-type Log types.FilteredLogEvent
+// Log is a single log event. LogGroupName is only set if logs of more than one
+// log group are fetched.
+type Log struct {
+	types.FilteredLogEvent
+	LogGroupName *string
+}
 
 type YamlLog struct {
-	EventId       *string `yaml:"event-id,omitempty"`
-	LogStreamName *string `yaml:"log-stream-name,omitempty"`
-	IngestionTime *int64  `yaml:"ingestion-time,omitempty"`
-	Timestamp     *int64  `yaml:"timestamp,omitempty"`
-	Message       map[string]interface{}
+	LogGroupName  *string                `yaml:"log-group-name,omitempty" json:"log-group-name,omitempty"`
+	EventId       *string                `yaml:"event-id,omitempty" json:"event-id,omitempty"`
+	LogStreamName *string                `yaml:"log-stream-name,omitempty" json:"log-stream-name,omitempty"`
+	IngestionTime *Millis                `yaml:"ingestion-time,omitempty" json:"ingestion-time,omitempty"`
+	Timestamp     *Millis                `yaml:"timestamp,omitempty" json:"timestamp,omitempty"`
+	Message       map[string]interface{} `json:"message"`
 }
 
 func (l Log) PrintOutTxt() {
@@ -34,70 +39,176 @@ func (l Log) PrintOutYml(filter ...string) error {
 	return nil
 }
 
-func (l Log) PrintTxtFile(file *os.File) (int, error) {
-	return file.WriteString(l.FormatedLine())
+func (l Log) PrintOutJson(filter ...string) error {
+	jsn, err := l.toJson(filter...)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(jsn))
+	return nil
+}
+
+func (l Log) PrintTxtFile(w io.Writer) (int, error) {
+	return io.WriteString(w, l.FormatedLine())
 }
 
-func (l Log) PrintYamlFile(file *os.File, filter ...string) (int, error) {
+func (l Log) PrintYamlFile(w io.Writer, filter ...string) (int, error) {
 	yml, err := l.toYaml(filter...)
 	if err != nil {
 		return 0, err
 	}
-	_, err = file.WriteString("---\n")
+	_, err = io.WriteString(w, "---\n")
 	if err != nil {
 		return 0, err
 	}
-	return file.Write(yml)
+	return w.Write(yml)
+}
+
+func (l Log) PrintJsonFile(w io.Writer, filter ...string) (int, error) {
+	jsn, err := l.toJson(filter...)
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(append(jsn, '\n'))
 }
 
 func (l Log) FormatedLine() string {
-	return fmt.Sprintf("%s : %s - %s\n", *l.EventId, time.UnixMilli(*l.Timestamp).Format(time.RFC3339), *l.Message)
+	if l.LogGroupName != nil {
+		return fmt.Sprintf("%s : %s : %s - %s\n", *l.LogGroupName, *l.EventId, formatMillis(*l.Timestamp), *l.Message)
+	}
+	return fmt.Sprintf("%s : %s - %s\n", *l.EventId, formatMillis(*l.Timestamp), *l.Message)
+}
+
+// ColoredLine returns FormatedLine with ANSI colors. The event ID has the color of
+// the log stream, the timestamp is dimmed and the level of the message highlighted.
+func (l Log) ColoredLine() string {
+	eventId := colorize(streamColor(stringValue(l.LogStreamName)), *l.EventId)
+	timestamp := colorize(colorCodes["dim"], formatMillis(*l.Timestamp))
+	message := highlightLevel(*l.Message)
+	if l.LogGroupName != nil {
+		return fmt.Sprintf("%s : %s : %s - %s\n", colorize(colorCodes["bold"], *l.LogGroupName), eventId, timestamp, message)
+	}
+	return fmt.Sprintf("%s : %s - %s\n", eventId, timestamp, message)
 }
 
 func (l Log) toYaml(filter ...string) ([]byte, error) {
-	yamlLog := &YamlLog{
-		EventId:       l.EventId,
-		LogStreamName: l.LogStreamName,
-		IngestionTime: l.IngestionTime,
-		Timestamp:     l.Timestamp,
+	yamlLog, err := l.toYamlLog(filter...)
+	if err != nil {
+		return nil, err
 	}
-	str, err := json2yaml(*l.Message)
+	return yaml.Marshal(yamlLog)
+}
+
+func (l Log) toJson(filter ...string) ([]byte, error) {
+	yamlLog, err := l.toYamlLog(filter...)
 	if err != nil {
 		return nil, err
 	}
-	err = yaml.Unmarshal([]byte(str), &yamlLog.Message)
+	return json.Marshal(yamlLog)
+}
+
+func (l Log) toYamlLog(filter ...string) (*YamlLog, error) {
+	message, err := l.messageMap()
 	if err != nil {
 		return nil, err
 	}
+	metadata := l.metadata()
 
 	if len(filter) > 0 {
-		// search for metadata filter
-		metadataFilters := []string{}
-		for _, key := range filter {
-			if strings.Contains(key, "metadata.") {
-				metadataFilter := strings.SplitAfterN(key, ".", 2)
-				if len(metadataFilter) == 2 {
-					metadataFilters = append(metadataFilters, strings.ToLower(metadataFilter[1]))
-				} else {
-					logrus.Errorf("filter definition %s can't be applied", key)
-				}
+		f := newFieldFilter(eventFilter(filter)...)
+		for name := range metadata {
+			if !f.keeps("metadata", name) {
+				delete(metadata, name)
 			}
 		}
-		if ok, _ := contains(metadataFilters, "timestamp"); !ok {
-			yamlLog.Timestamp = nil
-		}
-		if ok, _ := contains(metadataFilters, "ingestion-time"); !ok {
-			yamlLog.IngestionTime = nil
+
+		filterMap(message, filter...)
+	}
+
+	return &YamlLog{
+		LogGroupName:  metadataString(metadata, "log-group-name"),
+		EventId:       metadataString(metadata, "event-id"),
+		LogStreamName: metadataString(metadata, "log-stream-name"),
+		IngestionTime: metadataMillis(metadata, "ingestion-time"),
+		Timestamp:     metadataMillis(metadata, "timestamp"),
+		Message:       message,
+	}, nil
+}
+
+// eventFilter returns filter with the metadata fields lower cased, as they are
+// matched case insensitive.
+func eventFilter(filter []string) []string {
+	eventFilter := make([]string, len(filter))
+	for i, entry := range filter {
+		eventFilter[i] = entry
+		if strings.HasPrefix(strings.ToLower(strings.TrimPrefix(entry, "!")), "metadata.") {
+			eventFilter[i] = strings.ToLower(entry)
 		}
-		if ok, _ := contains(metadataFilters, "log-stream-name"); !ok {
-			yamlLog.LogStreamName = nil
+	}
+	return eventFilter
+}
+
+// metadataNames are the names of the metadata of an event, which is selected by
+// metadata.<name> in filter fields, csv columns, projections and conditions.
+var metadataNames = []string{"log-group-name", "event-id", "log-stream-name", "ingestion-time", "timestamp", "message"}
+
+// metadata returns the metadata of the event by the names in metadataNames.
+// Metadata which isn't set is missing. message is the unparsed message.
+func (l Log) metadata() map[string]interface{} {
+	m := map[string]interface{}{}
+	for name, value := range map[string]*string{
+		"log-group-name":  l.LogGroupName,
+		"event-id":        l.EventId,
+		"log-stream-name": l.LogStreamName,
+		"message":         l.Message,
+	} {
+		if value != nil {
+			m[name] = *value
 		}
-		if ok, _ := contains(metadataFilters, "event-id"); !ok {
-			yamlLog.EventId = nil
+	}
+	for name, value := range map[string]*int64{
+		"ingestion-time": l.IngestionTime,
+		"timestamp":      l.Timestamp,
+	} {
+		if value != nil {
+			m[name] = Millis(*value)
 		}
+	}
+	return m
+}
+
+func metadataString(metadata map[string]interface{}, name string) *string {
+	if s, ok := metadata[name].(string); ok {
+		return &s
+	}
+	return nil
+}
 
-		filterMap(yamlLog.Message, filter...)
+func metadataMillis(metadata map[string]interface{}, name string) *Millis {
+	if m, ok := metadata[name].(Millis); ok {
+		return &m
 	}
+	return nil
+}
 
-	return yaml.Marshal(yamlLog)
+// TextField is the message field which contains messages which are neither JSON
+// nor logfmt.
+const TextField = "text"
+
+// messageMap parses the message as JSON or logfmt. Any other message is returned as
+// TextField.
+func (l Log) messageMap() (map[string]interface{}, error) {
+	message := map[string]interface{}{}
+	str, err := json2yaml(*l.Message)
+	if err != nil {
+		if logfmt, ok := parseLogfmt(*l.Message); ok {
+			return logfmt, nil
+		}
+		return map[string]interface{}{TextField: *l.Message}, nil
+	}
+	err = yaml.Unmarshal([]byte(str), &message)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
 }
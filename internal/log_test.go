@@ -1,10 +1,12 @@
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
 	"path"
+	"strings"
 	"testing"
 	"time"
 
@@ -28,15 +30,44 @@ func setupLog() Log {
 		Message:       aws.String("{ \"kubernetes\": { \"Pod_Name\": \"xyz\", \"namespace\": \"something\" }, \"log\": \"something\"}"),
 		Timestamp:     aws.Int64(now),
 	}
-	return Log(event)
+	return Log{FilteredLogEvent: event}
 }
 
+// testLog returns a log with a fixed timestamp and the given stream and message.
+func testLog(stream, message string) Log {
+	return Log{FilteredLogEvent: types.FilteredLogEvent{
+		EventId:       aws.String(EVENTID),
+		LogStreamName: aws.String(stream),
+		Timestamp:     aws.Int64(1641135845123),
+		Message:       aws.String(message),
+	}}
+}
+
+var _ Printable = Log{}
+
 func TestFormatedLine(t *testing.T) {
 	log := setupLog()
 	line := log.FormatedLine()
 	assert.Equal(t, fmt.Sprintf("%s : %s - %s\n", *log.EventId, time.UnixMilli(*log.Timestamp).Format(time.RFC3339), *log.Message), line)
 }
 
+func TestFormatedLineWithLogGroup(t *testing.T) {
+	log := setupLog()
+	log.LogGroupName = aws.String("group")
+	line := log.FormatedLine()
+	assert.Equal(t, fmt.Sprintf("group : %s : %s - %s\n", *log.EventId, time.UnixMilli(*log.Timestamp).Format(time.RFC3339), *log.Message), line)
+}
+
+func TestColoredLine(t *testing.T) {
+	log := setupLog()
+	log.LogGroupName = aws.String("group")
+	log.Message = aws.String(`{"level":"warn"}`)
+	line := log.ColoredLine()
+	timestamp := time.UnixMilli(*log.Timestamp).Format(time.RFC3339)
+	expected := fmt.Sprintf("\x1b[1mgroup\x1b[0m : \x1b[%dm%s\x1b[0m : \x1b[2m%s\x1b[0m - {\"level\":\"\x1b[33mwarn\x1b[0m\"}\n", streamColor(LOGSTREAMNAME), EVENTID, timestamp)
+	assert.Equal(t, expected, line)
+}
+
 func TestPrintOutTxt(t *testing.T) {
 	log := setupLog()
 	log.PrintOutTxt()
@@ -48,6 +79,76 @@ func TestPrintOutYml(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestPrintOutJson(t *testing.T) {
+	log := setupLog()
+	err := log.PrintOutJson()
+	assert.NoError(t, err)
+}
+
+func TestPrintJsonFile(t *testing.T) {
+
+	t.Run("without filter", func(t *testing.T) {
+		log := setupLog()
+		file, err := os.OpenFile(path.Join(t.TempDir(), "test.json"), os.O_APPEND|os.O_CREATE|os.O_RDWR, fs.FileMode(0644))
+		assert.NoError(t, err)
+		written, err := log.PrintJsonFile(file)
+		assert.NoError(t, err)
+		assert.Greater(t, written, 0)
+		bt, err := os.ReadFile(file.Name())
+		assert.NoError(t, err)
+		assert.Equal(t, byte('\n'), bt[len(bt)-1])
+		logFromJson := &YamlLog{}
+		err = json.Unmarshal(bt, logFromJson)
+		assert.NoError(t, err)
+		assert.Equal(t, EVENTID, *logFromJson.EventId)
+		assert.Equal(t, LOGSTREAMNAME, *logFromJson.LogStreamName)
+		assert.Contains(t, logFromJson.Message, "kubernetes")
+	})
+
+	t.Run("complex filter", func(t *testing.T) {
+		log := setupLog()
+		file, err := os.OpenFile(path.Join(t.TempDir(), "test.json"), os.O_APPEND|os.O_CREATE|os.O_RDWR, fs.FileMode(0644))
+		assert.NoError(t, err)
+		_, err = log.PrintJsonFile(file, "log", "kubernetes.Pod_Name", "metadata.event-id")
+		assert.NoError(t, err)
+		_, err = log.PrintJsonFile(file, "log")
+		assert.NoError(t, err)
+		bt, err := os.ReadFile(file.Name())
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(bt)), "\n")
+		assert.Len(t, lines, 2)
+		logFromJson := &YamlLog{}
+		err = json.Unmarshal([]byte(lines[0]), logFromJson)
+		assert.NoError(t, err)
+		assert.Equal(t, EVENTID, *logFromJson.EventId)
+		assert.Nil(t, logFromJson.Timestamp)
+		assert.Contains(t, logFromJson.Message, "log")
+		assert.Contains(t, logFromJson.Message["kubernetes"], "Pod_Name")
+		assert.NotContains(t, logFromJson.Message["kubernetes"], "namespace")
+	})
+
+	t.Run("exclude filter", func(t *testing.T) {
+		log := setupLog()
+		file, err := os.OpenFile(path.Join(t.TempDir(), "test.json"), os.O_APPEND|os.O_CREATE|os.O_RDWR, fs.FileMode(0644))
+		assert.NoError(t, err)
+		_, err = log.PrintJsonFile(file, "!kubernetes.namespace", "!metadata.Event-Id", "!metadata.ingestion-time")
+		assert.NoError(t, err)
+		bt, err := os.ReadFile(file.Name())
+		assert.NoError(t, err)
+		logFromJson := &YamlLog{}
+		err = json.Unmarshal(bt, logFromJson)
+		assert.NoError(t, err)
+		assert.Nil(t, logFromJson.EventId)
+		assert.Nil(t, logFromJson.IngestionTime)
+		assert.Equal(t, LOGSTREAMNAME, *logFromJson.LogStreamName)
+		assert.NotNil(t, logFromJson.Timestamp)
+		assert.Equal(t, map[string]interface{}{
+			"log":        "something",
+			"kubernetes": map[string]interface{}{"Pod_Name": "xyz"},
+		}, logFromJson.Message)
+	})
+}
+
 func TestPrintOutYaml(t *testing.T) {
 
 	t.Run("without filter", func(t *testing.T) {
@@ -124,7 +225,40 @@ func TestPrintOutYaml(t *testing.T) {
 		assert.Nil(t, logFromYaml.EventId)
 		assert.Nil(t, logFromYaml.IngestionTime)
 		assert.Nil(t, logFromYaml.LogStreamName)
-		assert.WithinDuration(t, time.Now(), time.UnixMilli(*logFromYaml.Timestamp), 50*time.Millisecond)
+		assert.WithinDuration(t, time.Now(), time.UnixMilli(int64(*logFromYaml.Timestamp)), 50*time.Millisecond)
+	})
+}
+
+func TestToYamlMessageFormats(t *testing.T) {
+	t.Run("plain text", func(t *testing.T) {
+		log := setupLog()
+		log.Message = aws.String("START RequestId: 8f5 Version: $LATEST")
+		yml, err := log.toYaml()
+		assert.NoError(t, err)
+		logFromYaml := &YamlLog{}
+		assert.NoError(t, yaml.Unmarshal(yml, logFromYaml))
+		assert.Equal(t, "START RequestId: 8f5 Version: $LATEST", logFromYaml.Message[TextField])
+	})
+	t.Run("json which is no object", func(t *testing.T) {
+		log := setupLog()
+		log.Message = aws.String("[1, 2]")
+		yml, err := log.toYaml(TextField)
+		assert.NoError(t, err)
+		logFromYaml := &YamlLog{}
+		assert.NoError(t, yaml.Unmarshal(yml, logFromYaml))
+		assert.Equal(t, "[1, 2]", logFromYaml.Message[TextField])
+	})
+	t.Run("logfmt with filter", func(t *testing.T) {
+		log := setupLog()
+		log.Message = aws.String(`level=error http.status=500 http.path=/api msg="upstream timeout"`)
+		yml, err := log.toYaml("level", "http.status")
+		assert.NoError(t, err)
+		logFromYaml := &YamlLog{}
+		assert.NoError(t, yaml.Unmarshal(yml, logFromYaml))
+		assert.Equal(t, map[string]interface{}{
+			"level": "error",
+			"http":  map[string]interface{}{"status": 500},
+		}, logFromYaml.Message)
 	})
 }
 
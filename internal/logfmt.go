@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// parseLogfmt parses messages like `level=info msg="request done" duration_ms=12`.
+// It returns false if any part of the message isn't a key=value pair, so plain text
+// isn't taken for logfmt. Dotted keys like http.status are nested into maps to allow
+// using them with filter fields. Numbers and booleans which are not quoted are
+// converted.
+func parseLogfmt(str string) (map[string]interface{}, bool) {
+	m := map[string]interface{}{}
+	runes := []rune(strings.TrimSpace(str))
+	if len(runes) == 0 {
+		return nil, false
+	}
+
+	for i := 0; i < len(runes); {
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+
+		start := i
+		for i < len(runes) && runes[i] != '=' {
+			if unicode.IsSpace(runes[i]) || runes[i] == '"' {
+				return nil, false
+			}
+			i++
+		}
+		if i == start || i == len(runes) {
+			return nil, false
+		}
+		key := string(runes[start:i])
+		i++ // skip =
+
+		var value interface{}
+		if i < len(runes) && runes[i] == '"' {
+			i++
+			sb := strings.Builder{}
+			closed := false
+			for i < len(runes) && !closed {
+				switch r := runes[i]; {
+				case r == '\\' && i+1 < len(runes):
+					i++
+					switch runes[i] {
+					case 'n':
+						sb.WriteRune('\n')
+					case 't':
+						sb.WriteRune('\t')
+					default:
+						sb.WriteRune(runes[i])
+					}
+				case r == '"':
+					closed = true
+				default:
+					sb.WriteRune(r)
+				}
+				i++
+			}
+			if !closed || (i < len(runes) && !unicode.IsSpace(runes[i])) {
+				return nil, false
+			}
+			value = sb.String()
+		} else {
+			start = i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				if runes[i] == '"' {
+					return nil, false
+				}
+				i++
+			}
+			value = logfmtValue(string(runes[start:i]))
+		}
+
+		setPath(m, key, value)
+	}
+	return m, true
+}
+
+func logfmtValue(str string) interface{} {
+	if i, err := strconv.Atoi(str); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(str, 64); err == nil {
+		return f
+	}
+	if b, err := strconv.ParseBool(str); err == nil && (str == "true" || str == "false") {
+		return b
+	}
+	return str
+}
+
+// setPath sets a dotted key as nested maps. If a part of the path is already used
+// by a value the full key is set instead. Otherwise the last value wins.
+func setPath(m map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	current := m
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part]
+		if !ok {
+			sub := map[string]interface{}{}
+			current[part] = sub
+			current = sub
+			continue
+		}
+		sub, ok := next.(map[string]interface{})
+		if !ok {
+			m[key] = value
+			return
+		}
+		current = sub
+	}
+	current[parts[len(parts)-1]] = value
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	t.Run("simple", func(t *testing.T) {
+		m, ok := parseLogfmt(`level=info msg="request done" duration_ms=12 ratio=0.5 cached=false empty=`)
+		assert.True(t, ok)
+		assert.Equal(t, map[string]interface{}{
+			"level":       "info",
+			"msg":         "request done",
+			"duration_ms": 12,
+			"ratio":       0.5,
+			"cached":      false,
+			"empty":       "",
+		}, m)
+	})
+	t.Run("escaped quotes", func(t *testing.T) {
+		m, ok := parseLogfmt(`msg="say \"hi\"\nbye" id="42"`)
+		assert.True(t, ok)
+		assert.Equal(t, "say \"hi\"\nbye", m["msg"])
+		assert.Equal(t, "42", m["id"])
+	})
+	t.Run("dotted keys", func(t *testing.T) {
+		m, ok := parseLogfmt(`http.status=200 http.method=GET http.status.code=1`)
+		assert.True(t, ok)
+		assert.Equal(t, map[string]interface{}{
+			"http": map[string]interface{}{
+				"status": 200,
+				"method": "GET",
+			},
+			"http.status.code": 1,
+		}, m)
+		_, ok = m["http"].(map[string]interface{})
+		assert.True(t, ok)
+	})
+	t.Run("plain text", func(t *testing.T) {
+		for _, str := range []string{
+			"START RequestId: 8f5 Version: $LATEST",
+			`127.0.0.1 - - [02/Jan/2022:15:04:05 +0000] "GET / HTTP/1.1" 200 612`,
+			"key=value trailing",
+			`msg="unterminated`,
+			`msg="quoted"text`,
+			"=value",
+			"",
+		} {
+			_, ok := parseLogfmt(str)
+			assert.False(t, ok, str)
+		}
+	})
+}
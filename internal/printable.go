@@ -0,0 +1,21 @@
+package internal
+
+import "io"
+
+// Printable is implemented by everything which can be printed in all supported
+// output formats.
+type Printable interface {
+	CsvRow
+	PrintOutTxt()
+	PrintOutYml(filter ...string) error
+	PrintOutJson(filter ...string) error
+	PrintTxtFile(w io.Writer) (int, error)
+	PrintYamlFile(w io.Writer, filter ...string) (int, error)
+	PrintJsonFile(w io.Writer, filter ...string) (int, error)
+}
+
+// Colorable is implemented by everything which can be printed with colors in txt
+// output.
+type Colorable interface {
+	ColoredLine() string
+}
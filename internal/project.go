@@ -0,0 +1,695 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Projectable is implemented by everything which can be printed with a
+// Projection.
+type Projectable interface {
+	// ProjectionInput returns the document a Projection is evaluated on.
+	ProjectionInput() (map[string]interface{}, error)
+}
+
+// Projection builds a new object for each event from a jq like expression, e.g.
+// {pod: .kubernetes.pod_name, msg: .log, ts: .metadata.timestamp}.
+type Projection struct {
+	expr expr
+	// filter selects the fields of the input if set
+	filter []string
+}
+
+// expr evaluates an expression with the value piped into it.
+type expr func(input interface{}) (interface{}, error)
+
+// NewProjection parses text. It supports:
+//
+//	.                       the input
+//	.a.b, ."a b"            a field
+//	.a[0], .a[-1], .a["b"]  an array element or field
+//	{a: .x, b, "c d": .y}   an object, b is short for b: .b
+//	[.a, .b]                an array
+//	"text", 42, true, null  literals
+//	x | f                   pipes x into f
+//	x // y                  y if x is null or false
+//	(x)                     grouping
+//
+// and the functions length, keys, first, last, ascii_downcase, ascii_upcase,
+// tostring, tonumber, split(separator), join(separator) and time(layout).
+func NewProjection(text string) (*Projection, error) {
+	p := &projectionParser{scanner{kind: "projection", text: text}}
+	e, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.text) {
+		return nil, p.errorf("unexpected %q", p.text[p.pos:])
+	}
+	return &Projection{expr: e}, nil
+}
+
+// SetFilter selects the fields of the input like filter fields select the printed
+// fields, e.g. !metadata.message or kubernetes.*. Other fields are null in the
+// projection.
+func (p *Projection) SetFilter(filter ...string) {
+	p.filter = eventFilter(filter)
+}
+
+// Apply returns the projection of v.
+func (p *Projection) Apply(v Projectable) (interface{}, error) {
+	input, err := v.ProjectionInput()
+	if err != nil {
+		return nil, err
+	}
+	if len(p.filter) > 0 {
+		filterMap(input, p.filter...)
+	}
+	return p.expr(input)
+}
+
+// WriteYaml writes the projection of v as YAML document to w.
+func (p *Projection) WriteYaml(w io.Writer, v Projectable) error {
+	value, err := p.Apply(v)
+	if err != nil {
+		return err
+	}
+	yml, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "---\n"+string(yml))
+	return err
+}
+
+// WriteJson writes the projection of v as JSON line to w.
+func (p *Projection) WriteJson(w io.Writer, v Projectable) error {
+	value, err := p.Apply(v)
+	if err != nil {
+		return err
+	}
+	jsn, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(jsn, '\n'))
+	return err
+}
+
+// ProjectionInput returns the parsed message with the metadata of the event as
+// field metadata.
+func (l Log) ProjectionInput() (map[string]interface{}, error) {
+	input, err := l.messageMap()
+	if err != nil {
+		return nil, err
+	}
+	input["metadata"] = l.metadata()
+	return input, nil
+}
+
+// ProjectionInput returns the fields of the record by name.
+func (r Record) ProjectionInput() (map[string]interface{}, error) {
+	return r.toMap(), nil
+}
+
+// object keeps the order of the keys of an object in the output.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (o object) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range o.keys {
+		k, v := &yaml.Node{}, &yaml.Node{}
+		if err := k.Encode(key); err != nil {
+			return nil, err
+		}
+		if err := v.Encode(o.values[key]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, k, v)
+	}
+	return node, nil
+}
+
+type projectionParser struct {
+	scanner
+}
+
+func (p *projectionParser) parsePipe() (expr, error) {
+	left, err := p.parseAlternative()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("|") {
+		right, err := p.parseAlternative()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(input interface{}) (interface{}, error) {
+			value, err := l(input)
+			if err != nil {
+				return nil, err
+			}
+			return right(value)
+		}
+	}
+	return left, nil
+}
+
+func (p *projectionParser) parseAlternative() (expr, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("//") {
+		right, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(input interface{}) (interface{}, error) {
+			value, err := l(input)
+			if err != nil {
+				return nil, err
+			}
+			if value == nil || value == false {
+				return right(input)
+			}
+			return value, nil
+		}
+	}
+	return left, nil
+}
+
+// parsePostfix parses a primary expression followed by fields and indexes.
+func (p *projectionParser) parsePostfix() (expr, error) {
+	e, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		switch {
+		case strings.HasPrefix(p.text[p.pos:], "//"):
+			return e, nil
+		case p.consume("."):
+			next, err := p.parseField()
+			if err != nil {
+				return nil, err
+			}
+			e = chain(e, next)
+		case p.consume("["):
+			if e, err = p.parseIndex(e); err != nil {
+				return nil, err
+			}
+		default:
+			return e, nil
+		}
+	}
+}
+
+func chain(first, second expr) expr {
+	return func(input interface{}) (interface{}, error) {
+		value, err := first(input)
+		if err != nil {
+			return nil, err
+		}
+		return second(value)
+	}
+}
+
+func (p *projectionParser) parsePrimary() (expr, error) {
+	p.skipSpace()
+	if p.pos >= len(p.text) {
+		return nil, p.errorf("unexpected end")
+	}
+	c := p.text[p.pos]
+	switch {
+	case c == '.':
+		p.pos++
+		if p.pos < len(p.text) && (isFieldChar(p.text[p.pos]) || p.text[p.pos] == '"') {
+			return p.parseField()
+		}
+		if p.consume("[") {
+			return p.parseIndex(identity)
+		}
+		return identity, nil
+	case c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return constant(s), nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		n, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		return constant(n), nil
+	case c == '{':
+		p.pos++
+		return p.parseObject()
+	case c == '[':
+		p.pos++
+		return p.parseArray()
+	case c == '(':
+		p.pos++
+		e, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case isFieldChar(c):
+		return p.parseFunction()
+	}
+	return nil, p.errorf("unexpected %q", string(c))
+}
+
+func identity(input interface{}) (interface{}, error) {
+	return input, nil
+}
+
+func constant(value interface{}) expr {
+	return func(interface{}) (interface{}, error) {
+		return value, nil
+	}
+}
+
+// isFieldChar returns true for the characters of unquoted field names. - is
+// included as it's part of the metadata field names.
+func isFieldChar(c byte) bool {
+	return c == '_' || c == '-' || c == '@' || c == '$' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func (p *projectionParser) parseName() string {
+	start := p.pos
+	for p.pos < len(p.text) && isFieldChar(p.text[p.pos]) {
+		p.pos++
+	}
+	return p.text[start:p.pos]
+}
+
+// parseField parses the name after a dot.
+func (p *projectionParser) parseField() (expr, error) {
+	var name string
+	if p.pos < len(p.text) && p.text[p.pos] == '"' {
+		var err error
+		if name, err = p.parseString(); err != nil {
+			return nil, err
+		}
+	} else if name = p.parseName(); name == "" {
+		return nil, p.errorf("expected field name")
+	}
+	return func(input interface{}) (interface{}, error) {
+		return member(input, name)
+	}, nil
+}
+
+// parseIndex parses the index after [ of the value of container. Like in jq the
+// index is evaluated on the same input as container, so .a[.i] is the element .i
+// of .a.
+func (p *projectionParser) parseIndex(container expr) (expr, error) {
+	index, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return func(input interface{}) (interface{}, error) {
+		value, err := container(input)
+		if err != nil {
+			return nil, err
+		}
+		i, err := index(input)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := i.(string); ok {
+			return member(value, s)
+		}
+		if n, ok := number(i); ok {
+			return element(value, int(n))
+		}
+		return nil, fmt.Errorf("can't index with %T", i)
+	}, nil
+}
+
+func member(input interface{}, name string) (interface{}, error) {
+	switch t := input.(type) {
+	case map[string]interface{}:
+		return t[name], nil
+	case object:
+		return t.values[name], nil
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("can't get field %s of %T", name, input)
+}
+
+// element returns the element i of an array. Negative indexes count from the end.
+func element(input interface{}, i int) (interface{}, error) {
+	switch t := input.(type) {
+	case []interface{}:
+		if i < 0 {
+			i += len(t)
+		}
+		if i < 0 || i >= len(t) {
+			return nil, nil
+		}
+		return t[i], nil
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("can't get element %d of %T", i, input)
+}
+
+func (p *projectionParser) parseObject() (expr, error) {
+	keys := []string{}
+	values := map[string]expr{}
+	if p.consume("}") {
+		return constant(object{values: map[string]interface{}{}}), nil
+	}
+	for {
+		p.skipSpace()
+		var key string
+		if p.pos < len(p.text) && p.text[p.pos] == '"' {
+			var err error
+			if key, err = p.parseString(); err != nil {
+				return nil, err
+			}
+		} else if key = p.parseName(); key == "" {
+			return nil, p.errorf("expected key")
+		}
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		if p.consume(":") {
+			value, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			values[key] = value
+		} else {
+			name := key
+			values[key] = func(input interface{}) (interface{}, error) {
+				return member(input, name)
+			}
+		}
+		if p.consume("}") {
+			break
+		}
+		if !p.consume(",") {
+			return nil, p.expected(", or }")
+		}
+	}
+	return func(input interface{}) (interface{}, error) {
+		o := object{keys: keys, values: map[string]interface{}{}}
+		for _, key := range keys {
+			value, err := values[key](input)
+			if err != nil {
+				return nil, err
+			}
+			o.values[key] = value
+		}
+		return o, nil
+	}, nil
+}
+
+func (p *projectionParser) parseArray() (expr, error) {
+	elements := []expr{}
+	if !p.consume("]") {
+		for {
+			e, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, e)
+			if p.consume("]") {
+				break
+			}
+			if !p.consume(",") {
+				return nil, p.expected(", or ]")
+			}
+		}
+	}
+	return func(input interface{}) (interface{}, error) {
+		values := make([]interface{}, len(elements))
+		for i, e := range elements {
+			var err error
+			if values[i], err = e(input); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}, nil
+}
+
+// parseFunction parses a keyword or a function call with arguments separated by ;
+// like in jq.
+func (p *projectionParser) parseFunction() (expr, error) {
+	start := p.pos
+	name := p.parseName()
+	switch name {
+	case "true":
+		return constant(true), nil
+	case "false":
+		return constant(false), nil
+	case "null":
+		return constant(nil), nil
+	}
+	args := []expr{}
+	p.skipSpace()
+	if p.consume("(") {
+		for {
+			arg, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.consume(")") {
+				break
+			}
+			if !p.consume(";") {
+				return nil, p.expected("; or )")
+			}
+		}
+	}
+	f, ok := projectionFuncs[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown function %s", name)
+	}
+	if len(args) != f.args {
+		p.pos = start
+		return nil, p.errorf("%s expects %d arguments", name, f.args)
+	}
+	return func(input interface{}) (interface{}, error) {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			var err error
+			if values[i], err = arg(input); err != nil {
+				return nil, err
+			}
+		}
+		return f.call(input, values...)
+	}, nil
+}
+
+type projectionFunc struct {
+	args int
+	call func(input interface{}, args ...interface{}) (interface{}, error)
+}
+
+var projectionFuncs = map[string]projectionFunc{
+	"length":         {0, length},
+	"keys":           {0, keys},
+	"first":          {0, func(input interface{}, _ ...interface{}) (interface{}, error) { return element(input, 0) }},
+	"last":           {0, func(input interface{}, _ ...interface{}) (interface{}, error) { return element(input, -1) }},
+	"ascii_downcase": {0, mapString(strings.ToLower)},
+	"ascii_upcase":   {0, mapString(strings.ToUpper)},
+	"tostring":       {0, tostring},
+	"tonumber":       {0, tonumber},
+	"split":          {1, split},
+	"join":           {1, join},
+	"time":           {1, projectTime},
+}
+
+func length(input interface{}, _ ...interface{}) (interface{}, error) {
+	switch t := input.(type) {
+	case nil:
+		return float64(0), nil
+	case string:
+		return float64(len([]rune(t))), nil
+	case []interface{}:
+		return float64(len(t)), nil
+	case map[string]interface{}:
+		return float64(len(t)), nil
+	case object:
+		return float64(len(t.keys)), nil
+	}
+	if n, ok := number(input); ok {
+		return math.Abs(n), nil
+	}
+	return nil, fmt.Errorf("%T has no length", input)
+}
+
+// keys returns the keys of an object sorted like jq does.
+func keys(input interface{}, _ ...interface{}) (interface{}, error) {
+	var names []string
+	switch t := input.(type) {
+	case map[string]interface{}:
+		for key := range t {
+			names = append(names, key)
+		}
+	case object:
+		names = append(names, t.keys...)
+	case nil:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%T has no keys", input)
+	}
+	sort.Strings(names)
+	result := make([]interface{}, len(names))
+	for i, name := range names {
+		result[i] = name
+	}
+	return result, nil
+}
+
+func mapString(f func(string) string) func(interface{}, ...interface{}) (interface{}, error) {
+	return func(input interface{}, _ ...interface{}) (interface{}, error) {
+		switch t := input.(type) {
+		case string:
+			return f(t), nil
+		case nil:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%T is not a string", input)
+	}
+}
+
+func tostring(input interface{}, _ ...interface{}) (interface{}, error) {
+	switch t := input.(type) {
+	case string:
+		return t, nil
+	case Millis:
+		return t.String(), nil
+	}
+	jsn, err := json.Marshal(input)
+	return string(jsn), err
+}
+
+func tonumber(input interface{}, _ ...interface{}) (interface{}, error) {
+	if n, ok := number(input); ok {
+		return n, nil
+	}
+	switch t := input.(type) {
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(t), 64)
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("can't convert %T to number", input)
+}
+
+// number returns the numeric types of parsed messages and metadata as float64.
+func number(value interface{}) (float64, bool) {
+	switch t := value.(type) {
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case Millis:
+		return float64(t), true
+	}
+	return 0, false
+}
+
+func split(input interface{}, args ...interface{}) (interface{}, error) {
+	s, ok := input.(string)
+	if input == nil {
+		return nil, nil
+	}
+	separator, sok := args[0].(string)
+	if !ok || !sok {
+		return nil, fmt.Errorf("split expects strings but got %T and %T", input, args[0])
+	}
+	parts := strings.Split(s, separator)
+	result := make([]interface{}, len(parts))
+	for i, part := range parts {
+		result[i] = part
+	}
+	return result, nil
+}
+
+func join(input interface{}, args ...interface{}) (interface{}, error) {
+	if input == nil {
+		return nil, nil
+	}
+	elements, ok := input.([]interface{})
+	separator, sok := args[0].(string)
+	if !ok || !sok {
+		return nil, fmt.Errorf("join expects an array and a string but got %T and %T", input, args[0])
+	}
+	parts := make([]string, len(elements))
+	for i, e := range elements {
+		parts[i] = text(e)
+	}
+	return strings.Join(parts, separator), nil
+}
+
+// projectTime formats a timestamp with the layout, which can also be rfc3339,
+// rfc3339nano or kitchen.
+func projectTime(input interface{}, args ...interface{}) (interface{}, error) {
+	layout, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("time expects a layout but got %T", args[0])
+	}
+	if m, ok := input.(Millis); ok {
+		input = int64(m)
+	}
+	if input == nil {
+		return nil, nil
+	}
+	return formatTime(TimeLayout(layout), input)
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ Projectable = Log{}
+var _ Projectable = Record{}
+
+const projectionMessage = `{"log": "Request Timeout", "kubernetes": {"pod_name": "gw-1", "labels": {"app": "gw", "tier": "api"}}, "containers": [{"name": "gw"}, {"name": "proxy"}], "tags": ["a", "b", "c"], "duration": "1.5"}`
+
+func projectJson(t *testing.T, text string, p Projectable) string {
+	projection, err := NewProjection(text)
+	assert.NoError(t, err)
+	sb := &strings.Builder{}
+	assert.NoError(t, projection.WriteJson(sb, p))
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func TestProjection(t *testing.T) {
+	log := testLog(LOGSTREAMNAME, projectionMessage)
+
+	t.Run("object with renamed fields", func(t *testing.T) {
+		assert.Equal(t, `{"pod":"gw-1","msg":"Request Timeout","ts":1641135845123}`,
+			projectJson(t, `{pod: .kubernetes.pod_name, msg: .log, ts: .metadata.timestamp}`, log))
+	})
+	t.Run("shorthand and quoted keys", func(t *testing.T) {
+		assert.Equal(t, `{"log":"Request Timeout","event id":"1234","stream":"logstream"}`,
+			projectJson(t, `{log, "event id": .metadata.event-id, stream: .metadata."log-stream-name"}`, log))
+	})
+	t.Run("array indexes", func(t *testing.T) {
+		assert.Equal(t, `{"first":"gw","last":"c","index":"b","missing":null}`,
+			projectJson(t, `{first: .containers[0].name, last: .tags[-1], index: .tags | .[1], missing: .tags[5]}`, log))
+	})
+	t.Run("index from the input", func(t *testing.T) {
+		log := Log{FilteredLogEvent: types.FilteredLogEvent{Message: aws.String(`{"tags": ["a", "b"], "i": 1, "key": "pod", "labels": {"pod": "gw-1"}}`)}}
+		assert.Equal(t, `{"tag":"b","label":"gw-1"}`,
+			projectJson(t, `{tag: .tags[.i], label: .labels[.key]}`, log))
+	})
+	t.Run("filter fields", func(t *testing.T) {
+		projection, err := NewProjection(`{pod: .kubernetes.pod_name, labels: .kubernetes.labels, log, stream: .metadata.log-stream-name, ts: .metadata.timestamp}`)
+		assert.NoError(t, err)
+		projection.SetFilter("kubernetes.*", "!kubernetes.labels", "Metadata.Timestamp")
+		sb := &strings.Builder{}
+		assert.NoError(t, projection.WriteJson(sb, log))
+		assert.Equal(t, `{"pod":"gw-1","labels":null,"log":null,"stream":null,"ts":1641135845123}`+"\n", sb.String())
+	})
+	t.Run("functions", func(t *testing.T) {
+		assert.Equal(t, `{"tags":"a,b,c","count":3,"labels":["app","tier"],"log":"request timeout","duration":1.5,"parts":["Request","Timeout"]}`,
+			projectJson(t, `{tags: .tags | join(","), count: .tags | length, labels: .kubernetes.labels | keys, log: .log | ascii_downcase, duration: .duration | tonumber, parts: .log | split(" ")}`, log))
+	})
+	t.Run("time", func(t *testing.T) {
+		SetTimeFormat(time.UTC, "")
+		defer SetTimeFormat(nil, "")
+		assert.Equal(t, `{"ts":"2022-01-02 15:04:05.123","rfc":"2022-01-02T15:04:05Z"}`,
+			projectJson(t, `{ts: .metadata.timestamp | time("2006-01-02 15:04:05.000"), rfc: .metadata.timestamp | time("rfc3339")}`, log))
+	})
+	t.Run("alternative and literals", func(t *testing.T) {
+		assert.Equal(t, `{"level":"info","ok":true,"n":-1,"list":["gw-1",null]}`,
+			projectJson(t, `{level: .level // "info", ok: true, n: -1, list: [.kubernetes.pod_name, null]}`, log))
+	})
+	t.Run("nested objects", func(t *testing.T) {
+		assert.Equal(t, `{"k8s":{"pod":"gw-1","app":"gw"}}`,
+			projectJson(t, `{k8s: .kubernetes | {pod: .pod_name, app: .labels.app}}`, log))
+	})
+	t.Run("single value", func(t *testing.T) {
+		assert.Equal(t, `"gw-1"`, projectJson(t, `.kubernetes.pod_name`, log))
+		assert.Equal(t, `{"app":"gw","tier":"api"}`, projectJson(t, `.kubernetes.labels`, log))
+	})
+	t.Run("plain text message", func(t *testing.T) {
+		log := Log{FilteredLogEvent: types.FilteredLogEvent{Message: aws.String("START RequestId: 1")}}
+		assert.Equal(t, `{"text":"START RequestId: 1","raw":"START RequestId: 1"}`,
+			projectJson(t, `{text, raw: .metadata.message}`, log))
+	})
+	t.Run("record", func(t *testing.T) {
+		record := Record{
+			{Field: aws.String("count()"), Value: aws.String("42")},
+			{Field: aws.String("@ptr"), Value: aws.String("ptr")},
+		}
+		assert.Equal(t, `{"count":42}`, projectJson(t, `{count: ."count()" | tonumber}`, record))
+	})
+	t.Run("evaluation error", func(t *testing.T) {
+		projection, err := NewProjection(`.log | join(",")`)
+		assert.NoError(t, err)
+		assert.EqualError(t, projection.WriteJson(&strings.Builder{}, log), "join expects an array and a string but got string and string")
+	})
+}
+
+func TestProjectionYaml(t *testing.T) {
+	projection, err := NewProjection(`{pod: .kubernetes.pod_name, msg: .log, tags: .tags}`)
+	assert.NoError(t, err)
+	sb := &strings.Builder{}
+	assert.NoError(t, projection.WriteYaml(sb, testLog(LOGSTREAMNAME, projectionMessage)))
+	assert.Equal(t, "---\npod: gw-1\nmsg: Request Timeout\ntags:\n    - a\n    - b\n    - c\n", sb.String())
+}
+
+func TestNewProjectionErrors(t *testing.T) {
+	for text, expected := range map[string]string{
+		`{pod: .kubernetes.pod_name`: "can't parse projection at 26: expected , or }",
+		`{pod .kubernetes}`:          `can't parse projection at 5: expected , or } but found "."`,
+		`.log | unknown`:             "can't parse projection at 7: unknown function unknown",
+		`.log | join`:                "can't parse projection at 7: join expects 1 arguments",
+		`.tags[0`:                    "can't parse projection at 7: expected ]",
+		`"open`:                      "can't parse projection at 0: unterminated string",
+		`.log )`:                     `can't parse projection at 5: unexpected ")"`,
+		``:                           "can't parse projection at 0: unexpected end",
+	} {
+		_, err := NewProjection(text)
+		assert.EqualError(t, err, expected, text)
+	}
+}
+
+func TestObjectMarshal(t *testing.T) {
+	o := object{keys: []string{"b", "a"}, values: map[string]interface{}{"a": "a", "b": 1}}
+	jsn, err := json.Marshal(o)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"b":1,"a":"a"}`, string(jsn))
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Record is a row of named fields, e.g. a result row of a CloudWatch Logs Insights
+// query or the description of a log group.
+type Record []types.ResultField
+
+// ptrField is added by Insights to every row and only useful for GetLogRecord.
+const ptrField = "@ptr"
+
+func (r Record) PrintOutTxt() {
+	fmt.Println(r.FormatedLine())
+}
+
+func (r Record) PrintOutYml(filter ...string) error {
+	yml, err := yaml.Marshal(r.toMap(filter...))
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(yml))
+	return nil
+}
+
+func (r Record) PrintOutJson(filter ...string) error {
+	jsn, err := json.Marshal(r.toMap(filter...))
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(jsn))
+	return nil
+}
+
+func (r Record) PrintTxtFile(w io.Writer) (int, error) {
+	return io.WriteString(w, r.FormatedLine())
+}
+
+func (r Record) PrintYamlFile(w io.Writer, filter ...string) (int, error) {
+	yml, err := yaml.Marshal(r.toMap(filter...))
+	if err != nil {
+		return 0, err
+	}
+	_, err = io.WriteString(w, "---\n")
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(yml)
+}
+
+func (r Record) PrintJsonFile(w io.Writer, filter ...string) (int, error) {
+	jsn, err := json.Marshal(r.toMap(filter...))
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(append(jsn, '\n'))
+}
+
+func (r Record) FormatedLine() string {
+	fields := []string{}
+	for _, field := range r {
+		if field.Field == nil || *field.Field == ptrField {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%s=%s", *field.Field, fieldValue(field)))
+	}
+	return strings.Join(fields, " ") + "\n"
+}
+
+func (r Record) CsvColumns() []string {
+	columns := []string{}
+	for _, field := range r {
+		if field.Field == nil || *field.Field == ptrField {
+			continue
+		}
+		columns = append(columns, *field.Field)
+	}
+	return columns
+}
+
+func (r Record) CsvValues(columns []string) ([]string, error) {
+	m := r.toMap()
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		if value, ok := m[column]; ok {
+			row[i] = fmt.Sprint(value)
+		}
+	}
+	return row, nil
+}
+
+func (r Record) toMap(filter ...string) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, field := range r {
+		if field.Field == nil || *field.Field == ptrField {
+			continue
+		}
+		m[*field.Field] = fieldValue(field)
+	}
+	if len(filter) > 0 {
+		filterMap(m, filter...)
+	}
+	return m
+}
+
+// fieldValue returns the value of field. Insights timestamps are converted to the time
+// format which was set.
+func fieldValue(field types.ResultField) string {
+	switch *field.Field {
+	case "@timestamp", "@ingestionTime":
+		return formatInsightsTime(stringValue(field.Value))
+	}
+	return stringValue(field.Value)
+}
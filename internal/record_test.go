@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+var _ Printable = Record{}
+
+func setupRecord() Record {
+	return Record{
+		{Field: aws.String("bin(5m)"), Value: aws.String("2022-01-02 15:00:00.000")},
+		{Field: aws.String("count()"), Value: aws.String("42")},
+		{Field: aws.String("@ptr"), Value: aws.String("CmAKJwoj")},
+	}
+}
+
+func TestRecordFormatedLine(t *testing.T) {
+	result := setupRecord()
+	assert.Equal(t, "bin(5m)=2022-01-02 15:00:00.000 count()=42\n", result.FormatedLine())
+}
+
+func TestRecordPrintOut(t *testing.T) {
+	result := setupRecord()
+	result.PrintOutTxt()
+	assert.NoError(t, result.PrintOutYml())
+	assert.NoError(t, result.PrintOutJson("count()"))
+}
+
+func TestRecordPrintFile(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		result := setupRecord()
+		file, err := os.OpenFile(path.Join(t.TempDir(), "test.yml"), os.O_APPEND|os.O_CREATE|os.O_RDWR, fs.FileMode(0644))
+		assert.NoError(t, err)
+		written, err := result.PrintYamlFile(file)
+		assert.NoError(t, err)
+		assert.Greater(t, written, 0)
+		bt, err := os.ReadFile(file.Name())
+		assert.NoError(t, err)
+		m := map[string]interface{}{}
+		assert.NoError(t, yaml.Unmarshal(bt, &m))
+		assert.Equal(t, "42", m["count()"])
+		assert.NotContains(t, m, "@ptr")
+	})
+
+	t.Run("json with filter", func(t *testing.T) {
+		result := setupRecord()
+		file, err := os.OpenFile(path.Join(t.TempDir(), "test.json"), os.O_APPEND|os.O_CREATE|os.O_RDWR, fs.FileMode(0644))
+		assert.NoError(t, err)
+		_, err = result.PrintJsonFile(file, "count()")
+		assert.NoError(t, err)
+		bt, err := os.ReadFile(file.Name())
+		assert.NoError(t, err)
+		m := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(bt, &m))
+		assert.Equal(t, map[string]interface{}{"count()": "42"}, m)
+	})
+
+	t.Run("txt", func(t *testing.T) {
+		result := setupRecord()
+		file, err := os.OpenFile(path.Join(t.TempDir(), "test.txt"), os.O_APPEND|os.O_CREATE|os.O_RDWR, fs.FileMode(0644))
+		assert.NoError(t, err)
+		written, err := result.PrintTxtFile(file)
+		assert.NoError(t, err)
+		assert.Greater(t, written, 0)
+	})
+}
+
+func TestRecordCsv(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sut := NewCsvWriter(buf, ',')
+	assert.NoError(t, sut.Write(setupRecord()))
+	assert.NoError(t, sut.Write(Record{
+		{Field: aws.String("count()"), Value: aws.String("7")},
+	}))
+	assert.NoError(t, sut.Flush())
+
+	records, err := csv.NewReader(buf).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"bin(5m)", "count()"},
+		{"2022-01-02 15:00:00.000", "42"},
+		{"", "7"},
+	}, records)
+}
+
+func TestRecordToMap(t *testing.T) {
+	result := Record{
+		{Field: aws.String("a"), Value: nil},
+		types.ResultField{},
+	}
+	assert.Equal(t, map[string]interface{}{"a": ""}, result.toMap())
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// scanner reads the expressions of projections and conditions.
+type scanner struct {
+	// kind is the kind of expression named in errors
+	kind string
+	text string
+	pos  int
+}
+
+func (s *scanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("can't parse %s at %d: %s", s.kind, s.pos, fmt.Sprintf(format, args...))
+}
+
+func (s *scanner) skipSpace() {
+	for s.pos < len(s.text) && unicode.IsSpace(rune(s.text[s.pos])) {
+		s.pos++
+	}
+}
+
+// consume skips token if the text continues with it.
+func (s *scanner) consume(token string) bool {
+	s.skipSpace()
+	if strings.HasPrefix(s.text[s.pos:], token) {
+		s.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (s *scanner) expect(token string) error {
+	if !s.consume(token) {
+		return s.expected(token)
+	}
+	return nil
+}
+
+func (s *scanner) expected(description string) error {
+	if s.pos >= len(s.text) {
+		return s.errorf("expected %s", description)
+	}
+	return s.errorf("expected %s but found %q", description, s.text[s.pos:s.pos+1])
+}
+
+// parseString parses a string in double quotes with the escapes of Go.
+func (s *scanner) parseString() (string, error) {
+	start := s.pos
+	s.pos++
+	for s.pos < len(s.text) && s.text[s.pos] != '"' {
+		if s.text[s.pos] == '\\' {
+			s.pos++
+		}
+		s.pos++
+	}
+	if s.pos >= len(s.text) {
+		s.pos = start
+		return "", s.errorf("unterminated string")
+	}
+	s.pos++
+	str, err := strconv.Unquote(s.text[start:s.pos])
+	if err != nil {
+		s.pos = start
+		return "", s.errorf("invalid string %s", s.text[start:s.pos])
+	}
+	return str, nil
+}
+
+// parseNumber parses a number. Numbers are float64 like in JSON.
+func (s *scanner) parseNumber() (float64, error) {
+	start := s.pos
+	s.pos++
+	for s.pos < len(s.text) && (s.text[s.pos] == '.' || (s.text[s.pos] >= '0' && s.text[s.pos] <= '9')) {
+		s.pos++
+	}
+	n, err := strconv.ParseFloat(s.text[start:s.pos], 64)
+	if err != nil {
+		s.pos = start
+		return 0, s.errorf("invalid number %s", s.text[start:s.pos])
+	}
+	return n, nil
+}
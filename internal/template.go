@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+)
+
+// Templatable is implemented by everything which can be printed with a
+// LineTemplate.
+type Templatable interface {
+	TemplateData() (interface{}, error)
+}
+
+// LogTemplateData is what a LineTemplate can access for a log event. Message
+// contains the parsed JSON or logfmt fields, Raw the unparsed message.
+type LogTemplateData struct {
+	LogGroupName  string
+	EventId       string
+	LogStreamName string
+	Timestamp     time.Time
+	IngestionTime time.Time
+	Message       map[string]interface{}
+	Raw           string
+}
+
+// LineTemplate formats each event of the txt output using a text/template.
+type LineTemplate struct {
+	tmpl *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"time":     formatTime,
+	"truncate": truncate,
+	"pad":      pad,
+	"padLeft":  padLeft,
+	"color":    color,
+	"json":     toJSON,
+	"default":  defaultValue,
+	"text":     text,
+}
+
+// NewLineTemplate parses text as template. Besides the builtin functions of
+// text/template it provides:
+//
+//	time <layout> <value>   formats a time or epoch milliseconds using a Go layout
+//	truncate <n> <value>    cuts the value after n characters
+//	pad <n> <value>         pads the value with spaces to n characters
+//	padLeft <n> <value>     pads the value with leading spaces to n characters
+//	color <name> <value>    colors the value, e.g. red, green, yellow, blue or dim
+//	json <value>            prints the value as JSON
+//	default <x> <value>     returns x if the value is missing or empty
+//
+// Missing fields, e.g. of messages which are neither JSON nor logfmt, are printed
+// as empty string.
+func NewLineTemplate(text string) (*LineTemplate, error) {
+	tmpl, err := template.New("line").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			printMissingEmpty(t.Tree.Root)
+		}
+	}
+	return &LineTemplate{tmpl: tmpl}, nil
+}
+
+// printMissingEmpty pipes the value of every action which prints it into text, so
+// missing fields aren't printed as <no value>.
+func printMissingEmpty(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			printMissingEmpty(child)
+		}
+	case *parse.ActionNode:
+		// actions declaring variables don't print anything
+		if len(n.Pipe.Decl) == 0 {
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+				NodeType: parse.NodeCommand,
+				Pos:      n.Pos,
+				Args:     []parse.Node{parse.NewIdentifier("text").SetPos(n.Pos)},
+			})
+		}
+	case *parse.IfNode:
+		printMissingEmpty(n.List)
+		printMissingEmpty(n.ElseList)
+	case *parse.RangeNode:
+		printMissingEmpty(n.List)
+		printMissingEmpty(n.ElseList)
+	case *parse.WithNode:
+		printMissingEmpty(n.List)
+		printMissingEmpty(n.ElseList)
+	}
+}
+
+// SetColor enables or disables the color function. If disabled, it returns the
+// value without colors.
+func (t *LineTemplate) SetColor(enabled bool) {
+	if enabled {
+		t.tmpl.Funcs(template.FuncMap{"color": color})
+		return
+	}
+	t.tmpl.Funcs(template.FuncMap{"color": func(name string, value interface{}) (string, error) {
+		if _, err := color(name, value); err != nil {
+			return "", err
+		}
+		return text(value), nil
+	}})
+}
+
+// Execute writes the formatted line for p to w. A newline is appended if the
+// template doesn't end with one.
+func (t *LineTemplate) Execute(w io.Writer, p Templatable) error {
+	data, err := p.TemplateData()
+	if err != nil {
+		return err
+	}
+	sb := &strings.Builder{}
+	if err := t.tmpl.Execute(sb, data); err != nil {
+		return err
+	}
+	line := sb.String()
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+	_, err = io.WriteString(w, line)
+	return err
+}
+
+// TemplateData returns the metadata and parsed message of the event.
+func (l Log) TemplateData() (interface{}, error) {
+	message, err := l.messageMap()
+	if err != nil {
+		return nil, err
+	}
+	data := LogTemplateData{
+		LogGroupName:  stringValue(l.LogGroupName),
+		EventId:       stringValue(l.EventId),
+		LogStreamName: stringValue(l.LogStreamName),
+		Message:       message,
+		Raw:           stringValue(l.Message),
+	}
+	if l.Timestamp != nil {
+		data.Timestamp = timestamps.in(time.UnixMilli(*l.Timestamp))
+	}
+	if l.IngestionTime != nil {
+		data.IngestionTime = timestamps.in(time.UnixMilli(*l.IngestionTime))
+	}
+	return data, nil
+}
+
+// TemplateData returns the fields of the record by name.
+func (r Record) TemplateData() (interface{}, error) {
+	return r.toMap(), nil
+}
+
+// text returns the string representation of a template value. Missing values are
+// returned as empty string.
+func text(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func formatTime(layout string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return timestamps.in(v).Format(layout), nil
+	case int64:
+		return timestamps.in(time.UnixMilli(v)).Format(layout), nil
+	case int:
+		return timestamps.in(time.UnixMilli(int64(v))).Format(layout), nil
+	case float64:
+		return timestamps.in(time.UnixMilli(int64(v))).Format(layout), nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return "", err
+		}
+		return timestamps.in(t).Format(layout), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("can't format %T as time", value)
+}
+
+func defaultValue(fallback, value interface{}) interface{} {
+	if value == nil || value == "" {
+		return fallback
+	}
+	return value
+}
+
+func truncate(n int, value interface{}) string {
+	s := text(value)
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func pad(n int, value interface{}) string {
+	return fmt.Sprintf("%-*s", n, text(value))
+}
+
+func padLeft(n int, value interface{}) string {
+	return fmt.Sprintf("%*s", n, text(value))
+}
+
+func color(name string, value interface{}) (string, error) {
+	code, ok := colorCodes[name]
+	if !ok {
+		return "", fmt.Errorf("unknown color %s", name)
+	}
+	return colorize(code, text(value)), nil
+}
+
+func toJSON(value interface{}) (string, error) {
+	jsn, err := json.Marshal(value)
+	return string(jsn), err
+}
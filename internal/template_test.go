@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ Templatable = Log{}
+var _ Templatable = Record{}
+
+func executeTemplate(t *testing.T, text string, p Templatable) string {
+	tmpl, err := NewLineTemplate(text)
+	assert.NoError(t, err)
+	sb := &strings.Builder{}
+	assert.NoError(t, tmpl.Execute(sb, p))
+	return sb.String()
+}
+
+func TestLineTemplate(t *testing.T) {
+	log := setupLog()
+	ts := time.UnixMilli(*log.Timestamp)
+
+	t.Run("metadata and message fields", func(t *testing.T) {
+		line := executeTemplate(t, `{{.Timestamp | time "15:04:05"}} {{.LogStreamName}} {{.Message.log}} {{.Message.kubernetes.namespace}}`, log)
+		assert.Equal(t, ts.Format("15:04:05")+" logstream something something\n", line)
+	})
+	t.Run("keeps trailing newline", func(t *testing.T) {
+		line := executeTemplate(t, "{{.EventId}}\n", log)
+		assert.Equal(t, "1234\n", line)
+	})
+	t.Run("raw and plain text message", func(t *testing.T) {
+		log := Log{FilteredLogEvent: types.FilteredLogEvent{Message: aws.String("START RequestId: 1")}}
+		line := executeTemplate(t, "{{.Raw}}|{{.Message.text}}", log)
+		assert.Equal(t, "START RequestId: 1|START RequestId: 1\n", line)
+	})
+	t.Run("missing fields of plain text message", func(t *testing.T) {
+		log := Log{FilteredLogEvent: types.FilteredLogEvent{Message: aws.String("START RequestId: 1")}}
+		line := executeTemplate(t, `[{{.Message.level}}][{{.Message.kubernetes.pod_name}}][{{.Message.level | default "info"}}][{{if .Message.log}}{{.Message.log}}{{else}}{{.Message.text | truncate 5}}{{end}}]`, log)
+		assert.Equal(t, "[][][info][START]\n", line)
+	})
+	t.Run("missing fields in range and variables", func(t *testing.T) {
+		log := Log{FilteredLogEvent: types.FilteredLogEvent{Message: aws.String(`{"items": [{"a": 1}, {"b": 2}]}`)}}
+		line := executeTemplate(t, `{{range .Message.items}}[{{.a}}]{{end}}{{$pod := .Message.kubernetes.pod_name}}[{{$pod}}]`, log)
+		assert.Equal(t, "[1][][]\n", line)
+	})
+	t.Run("truncate and pad", func(t *testing.T) {
+		line := executeTemplate(t, `[{{.LogStreamName | truncate 3}}][{{.EventId | pad 6}}][{{.EventId | padLeft 6}}][{{.Message.missing | pad 2}}]`, log)
+		assert.Equal(t, "[log][1234  ][  1234][  ]\n", line)
+	})
+	t.Run("color", func(t *testing.T) {
+		line := executeTemplate(t, `{{.EventId | color "red"}}`, log)
+		assert.Equal(t, "\x1b[31m1234\x1b[0m\n", line)
+	})
+	t.Run("color disabled", func(t *testing.T) {
+		tmpl, err := NewLineTemplate(`{{.EventId | color "red"}}`)
+		assert.NoError(t, err)
+		tmpl.SetColor(false)
+		sb := &strings.Builder{}
+		assert.NoError(t, tmpl.Execute(sb, log))
+		assert.Equal(t, "1234\n", sb.String())
+
+		tmpl, err = NewLineTemplate(`{{.EventId | color "pink"}}`)
+		assert.NoError(t, err)
+		tmpl.SetColor(false)
+		assert.ErrorContains(t, tmpl.Execute(sb, log), "unknown color pink")
+	})
+	t.Run("json", func(t *testing.T) {
+		line := executeTemplate(t, `{{json .Message.kubernetes}}`, log)
+		assert.Equal(t, "{\"Pod_Name\":\"xyz\",\"namespace\":\"something\"}\n", line)
+	})
+	t.Run("record", func(t *testing.T) {
+		record := Record{{Field: aws.String("count()"), Value: aws.String("5")}}
+		line := executeTemplate(t, `{{index . "count()"}}`, record)
+		assert.Equal(t, "5\n", line)
+	})
+	t.Run("unknown color", func(t *testing.T) {
+		tmpl, err := NewLineTemplate(`{{.EventId | color "pink"}}`)
+		assert.NoError(t, err)
+		assert.ErrorContains(t, tmpl.Execute(&strings.Builder{}, log), "unknown color pink")
+	})
+	t.Run("invalid template", func(t *testing.T) {
+		_, err := NewLineTemplate("{{.EventId")
+		assert.Error(t, err)
+	})
+}
+
+func TestFormatTime(t *testing.T) {
+	ts := time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, value := range []interface{}{ts, ts.UnixMilli(), float64(ts.UnixMilli()), ts.Format(time.RFC3339)} {
+		formatted, err := formatTime(time.RFC3339, value)
+		assert.NoError(t, err)
+		parsed, err := time.Parse(time.RFC3339, formatted)
+		assert.NoError(t, err)
+		assert.True(t, ts.Equal(parsed))
+	}
+	// times in messages are shown in the time zone which was set
+	SetTimeFormat(time.FixedZone("CET", 3600), "")
+	formatted, err := formatTime("15:04 MST", ts.Format(time.RFC3339))
+	SetTimeFormat(nil, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "16:04 CET", formatted)
+
+	formatted, err = formatTime(time.RFC3339, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, formatted)
+	_, err = formatTime(time.RFC3339, true)
+	assert.EqualError(t, err, "can't format bool as time")
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EpochFormat prints timestamps as epoch milliseconds.
+const EpochFormat = "epoch"
+
+// insightsLayout is the layout of timestamps in Insights query results, which are
+// always in UTC.
+const insightsLayout = "2006-01-02 15:04:05.000"
+
+// timeFormat defines how all timestamps are printed.
+type timeFormat struct {
+	// location is nil if no timezone was set. Timestamps of events are printed
+	// in the local timezone then, Insights timestamps are kept.
+	location *time.Location
+	// layout is a Go time layout or EpochFormat. If it's empty, txt output uses
+	// RFC3339 and all other outputs epoch milliseconds.
+	layout string
+}
+
+var timestamps = timeFormat{}
+
+// SetTimeFormat sets the timezone and format of all printed timestamps. format is
+// rfc3339, rfc3339nano, epoch, kitchen or a Go time layout. A nil loc and an empty
+// format keep the default of each output.
+func SetTimeFormat(loc *time.Location, format string) {
+	timestamps = timeFormat{location: loc, layout: TimeLayout(format)}
+}
+
+// TimeLayout returns the Go time layout of the named formats rfc3339, rfc3339nano
+// and kitchen. Any other format is returned unchanged.
+func TimeLayout(format string) string {
+	switch strings.ToLower(format) {
+	case "rfc3339":
+		return time.RFC3339
+	case "rfc3339nano":
+		return time.RFC3339Nano
+	case "kitchen":
+		return time.Kitchen
+	case EpochFormat:
+		return EpochFormat
+	}
+	return format
+}
+
+func (f timeFormat) in(t time.Time) time.Time {
+	if f.location == nil {
+		return t.Local()
+	}
+	return t.In(f.location)
+}
+
+// formatMillis formats epoch milliseconds for txt output.
+func formatMillis(millis int64) string {
+	switch timestamps.layout {
+	case "":
+		return timestamps.in(time.UnixMilli(millis)).Format(time.RFC3339)
+	case EpochFormat:
+		return strconv.FormatInt(millis, 10)
+	}
+	return timestamps.in(time.UnixMilli(millis)).Format(timestamps.layout)
+}
+
+// formatInsightsTime converts a timestamp of an Insights query result to the
+// timezone and format which were set. Other values are returned unchanged.
+func formatInsightsTime(value string) string {
+	if timestamps.location == nil && timestamps.layout == "" {
+		return value
+	}
+	t, err := time.Parse(insightsLayout, value)
+	if err != nil {
+		return value
+	}
+	if timestamps.layout == "" {
+		return timestamps.in(t).Format(insightsLayout)
+	}
+	return formatMillis(t.UnixMilli())
+}
+
+// Millis is an epoch milliseconds timestamp in yaml, json and csv output. It's
+// printed as number unless a time format was set.
+type Millis int64
+
+func (m Millis) numeric() bool {
+	return timestamps.layout == "" || timestamps.layout == EpochFormat
+}
+
+func (m Millis) String() string {
+	if m.numeric() {
+		return strconv.FormatInt(int64(m), 10)
+	}
+	return formatMillis(int64(m))
+}
+
+func (m Millis) MarshalJSON() ([]byte, error) {
+	if m.numeric() {
+		return json.Marshal(int64(m))
+	}
+	return json.Marshal(m.String())
+}
+
+func (m Millis) MarshalYAML() (interface{}, error) {
+	if m.numeric() {
+		return int64(m), nil
+	}
+	return m.String(), nil
+}
+
+func (m *Millis) UnmarshalJSON(bt []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(bt, &value); err != nil {
+		return err
+	}
+	return m.parse(value)
+}
+
+func (m *Millis) UnmarshalYAML(node *yaml.Node) error {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	return m.parse(value)
+}
+
+// parse reads epoch milliseconds or RFC3339 times.
+func (m *Millis) parse(value interface{}) error {
+	switch v := value.(type) {
+	case float64:
+		*m = Millis(v)
+	case int:
+		*m = Millis(v)
+	case time.Time:
+		*m = Millis(v.UnixMilli())
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return err
+		}
+		*m = Millis(t.UnixMilli())
+	default:
+		return fmt.Errorf("can't read %T as timestamp", value)
+	}
+	return nil
+}
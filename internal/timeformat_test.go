@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTimeLayout(t *testing.T) {
+	assert.Equal(t, time.RFC3339, TimeLayout("RFC3339"))
+	assert.Equal(t, time.RFC3339Nano, TimeLayout("rfc3339nano"))
+	assert.Equal(t, time.Kitchen, TimeLayout("kitchen"))
+	assert.Equal(t, EpochFormat, TimeLayout("epoch"))
+	assert.Equal(t, "15:04", TimeLayout("15:04"))
+}
+
+func TestTimeFormat(t *testing.T) {
+	t.Cleanup(func() { SetTimeFormat(nil, "") })
+	ts := time.Date(2022, 1, 2, 15, 4, 5, 123000000, time.UTC)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	log := setupLog()
+	log.Timestamp = aws.Int64(ts.UnixMilli())
+	log.IngestionTime = nil
+	log.Message = aws.String("{\"log\": \"something\"}")
+
+	t.Run("default", func(t *testing.T) {
+		SetTimeFormat(nil, "")
+		assert.Equal(t, ts.Local().Format(time.RFC3339), formatMillis(ts.UnixMilli()))
+		jsn, err := log.toJson()
+		assert.NoError(t, err)
+		assert.Contains(t, string(jsn), `"timestamp":1641135845123`)
+		assert.Equal(t, "2022-01-02 15:04:05.000", formatInsightsTime("2022-01-02 15:04:05.000"))
+	})
+	t.Run("timezone", func(t *testing.T) {
+		SetTimeFormat(berlin, "")
+		assert.Equal(t, "2022-01-02T16:04:05+01:00", formatMillis(ts.UnixMilli()))
+		assert.Equal(t, "group : 1234 : 2022-01-02T16:04:05+01:00 - {\"log\": \"something\"}\n", Log{FilteredLogEvent: log.FilteredLogEvent, LogGroupName: aws.String("group")}.FormatedLine())
+		assert.Equal(t, "2022-01-02 16:04:05.000", formatInsightsTime("2022-01-02 15:04:05.000"))
+		assert.Equal(t, "not a time", formatInsightsTime("not a time"))
+	})
+	t.Run("layout", func(t *testing.T) {
+		SetTimeFormat(time.UTC, "2006-01-02 15:04:05.000")
+		jsn, err := log.toJson("metadata.timestamp")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"timestamp":"2022-01-02 15:04:05.123","message":{}}`, string(jsn))
+
+		yml, err := log.toYaml("metadata.timestamp")
+		assert.NoError(t, err)
+		assert.Equal(t, "timestamp: \"2022-01-02 15:04:05.123\"\nmessage: {}\n", string(yml))
+
+		values, err := log.CsvValues([]string{"metadata.timestamp"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"2022-01-02 15:04:05.123"}, values)
+
+		record := Record{{Field: aws.String("@timestamp"), Value: aws.String("2022-01-02 15:04:05.123")}}
+		assert.Equal(t, "@timestamp=2022-01-02 15:04:05.123\n", record.FormatedLine())
+	})
+	t.Run("epoch", func(t *testing.T) {
+		SetTimeFormat(nil, EpochFormat)
+		assert.Equal(t, "1641135845123", formatMillis(ts.UnixMilli()))
+		yml, err := log.toYaml("metadata.timestamp")
+		assert.NoError(t, err)
+		assert.Equal(t, "timestamp: 1641135845123\nmessage: {}\n", string(yml))
+		assert.Equal(t, "1641135845123", formatInsightsTime("2022-01-02 15:04:05.123"))
+	})
+	t.Run("template", func(t *testing.T) {
+		SetTimeFormat(berlin, "")
+		line := executeTemplate(t, `{{.Timestamp | time "15:04"}} {{time "15:04" 1641135845123}}`, log)
+		assert.Equal(t, "16:04 16:04\n", line)
+	})
+}
+
+func TestMillisUnmarshal(t *testing.T) {
+	t.Cleanup(func() { SetTimeFormat(nil, "") })
+	expected := Millis(1641135845123)
+
+	for _, format := range []string{"", time.RFC3339Nano} {
+		SetTimeFormat(time.UTC, format)
+		log := YamlLog{Timestamp: &expected}
+
+		jsn, err := json.Marshal(log)
+		assert.NoError(t, err)
+		fromJson := YamlLog{}
+		assert.NoError(t, json.Unmarshal(jsn, &fromJson))
+		assert.Equal(t, expected, *fromJson.Timestamp)
+
+		yml, err := yaml.Marshal(log)
+		assert.NoError(t, err)
+		fromYaml := YamlLog{}
+		assert.NoError(t, yaml.Unmarshal(yml, &fromYaml))
+		assert.Equal(t, expected, *fromYaml.Timestamp)
+	}
+
+	var m Millis
+	assert.EqualError(t, json.Unmarshal([]byte("true"), &m), "can't read bool as timestamp")
+	assert.Error(t, json.Unmarshal([]byte(`"yesterday"`), &m))
+}
@@ -0,0 +1,375 @@
+package internal
+
+import (
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Condition selects events by an expression on the parsed message and metadata,
+// e.g. level in ("error","fatal") && duration_ms > 500 or log =~ /timeout/i.
+type Condition struct {
+	expr operand
+}
+
+// operand evaluates a part of a condition for the input of an event.
+type operand func(input map[string]interface{}) interface{}
+
+// NewCondition parses text. It supports:
+//
+//	kubernetes.pod_name, tags.0     fields of the message, metadata.<name> for metadata
+//	"text", 42, true, null          literals
+//	==, !=, <, <=, >, >=            comparisons, numbers are compared numerically
+//	x in ("a", "b")                 true if x equals any of the values
+//	x =~ /regex/i, x !~ /regex/     regular expressions with the flags i, m and s
+//	&&, ||, !, ( )                  logical operators and grouping
+//
+// Missing fields are null. A field alone is true unless it's null or false.
+func NewCondition(text string) (*Condition, error) {
+	p := &conditionParser{scanner{kind: "condition", text: text}}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.text) {
+		return nil, p.errorf("unexpected %q", p.text[p.pos:])
+	}
+	return &Condition{expr: e}, nil
+}
+
+// Match returns true if v fulfills the condition.
+func (c *Condition) Match(v Projectable) (bool, error) {
+	input, err := v.ProjectionInput()
+	if err != nil {
+		return false, err
+	}
+	return truthy(c.expr(input)), nil
+}
+
+func truthy(value interface{}) bool {
+	return value != nil && value != false
+}
+
+type conditionParser struct {
+	scanner
+}
+
+func (p *conditionParser) parseOr() (operand, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(input map[string]interface{}) interface{} {
+			return truthy(l(input)) || truthy(right(input))
+		}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (operand, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(input map[string]interface{}) interface{} {
+			return truthy(l(input)) && truthy(right(input))
+		}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseNot() (operand, error) {
+	p.skipSpace()
+	if strings.HasPrefix(p.text[p.pos:], "!") && !strings.HasPrefix(p.text[p.pos:], "!=") && !strings.HasPrefix(p.text[p.pos:], "!~") {
+		p.pos++
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(input map[string]interface{}) interface{} {
+			return !truthy(e(input))
+		}, nil
+	}
+	return p.parseComparison()
+}
+
+var comparisons = map[string]func(a, b interface{}) bool{
+	"==": equal,
+	"!=": func(a, b interface{}) bool { return !equal(a, b) },
+	"<=": func(a, b interface{}) bool { c, ok := compare(a, b); return ok && c <= 0 },
+	">=": func(a, b interface{}) bool { c, ok := compare(a, b); return ok && c >= 0 },
+	"<":  func(a, b interface{}) bool { c, ok := compare(a, b); return ok && c < 0 },
+	">":  func(a, b interface{}) bool { c, ok := compare(a, b); return ok && c > 0 },
+}
+
+func (p *conditionParser) parseComparison() (operand, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	for _, op := range []string{"=~", "!~"} {
+		if p.consume(op) {
+			re, err := p.parseRegexp()
+			if err != nil {
+				return nil, err
+			}
+			negate := op == "!~"
+			return func(input map[string]interface{}) interface{} {
+				value := left(input)
+				return value != nil && re.MatchString(text(value)) != negate
+			}, nil
+		}
+	}
+	// longer operators first, so <= isn't parsed as <
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			cmp := comparisons[op]
+			return func(input map[string]interface{}) interface{} {
+				return cmp(left(input), right(input))
+			}, nil
+		}
+	}
+	if p.consumeKeyword("in") {
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return func(input map[string]interface{}) interface{} {
+			value := left(input)
+			for _, v := range values {
+				if equal(value, v(input)) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+	return left, nil
+}
+
+// consumeKeyword skips the keyword if it's not the start of a longer name.
+func (p *conditionParser) consumeKeyword(keyword string) bool {
+	p.skipSpace()
+	end := p.pos + len(keyword)
+	if !strings.HasPrefix(p.text[p.pos:], keyword) || (end < len(p.text) && isFieldChar(p.text[end])) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *conditionParser) parseList() ([]operand, error) {
+	if !p.consume("(") {
+		return nil, p.expected("(")
+	}
+	values := []operand{}
+	for {
+		value, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.consume(")") {
+			return values, nil
+		}
+		if !p.consume(",") {
+			return nil, p.expected(", or )")
+		}
+	}
+}
+
+func (p *conditionParser) parseOperand() (operand, error) {
+	p.skipSpace()
+	if p.pos >= len(p.text) {
+		return nil, p.errorf("unexpected end")
+	}
+	c := p.text[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.expected(")")
+		}
+		return e, nil
+	case c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return literal(s), nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		n, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		return literal(n), nil
+	case isFieldChar(c):
+		return p.parseField(), nil
+	}
+	return nil, p.errorf("unexpected %q", string(c))
+}
+
+func literal(value interface{}) operand {
+	return func(map[string]interface{}) interface{} {
+		return value
+	}
+}
+
+// parseField parses a dotted path or one of the keywords true, false and null.
+func (p *conditionParser) parseField() operand {
+	start := p.pos
+	for p.pos < len(p.text) && (isFieldChar(p.text[p.pos]) || p.text[p.pos] == '.') {
+		p.pos++
+	}
+	name := p.text[start:p.pos]
+	switch name {
+	case "true":
+		return literal(true)
+	case "false":
+		return literal(false)
+	case "null":
+		return literal(nil)
+	}
+	path := strings.Split(name, ".")
+	return func(input map[string]interface{}) interface{} {
+		return lookup(input, path)
+	}
+}
+
+// lookup returns the value at path. Numeric keys select array elements.
+func lookup(input map[string]interface{}, path []string) interface{} {
+	var value interface{} = input
+	for _, key := range path {
+		switch t := value.(type) {
+		case map[string]interface{}:
+			value = t[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil
+			}
+			value = t[i]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// parseRegexp parses /regex/flags or a string containing the regex.
+func (p *conditionParser) parseRegexp() (*regexp.Regexp, error) {
+	p.skipSpace()
+	start := p.pos
+	var expr string
+	switch {
+	case strings.HasPrefix(p.text[p.pos:], `"`):
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		expr = s
+	case strings.HasPrefix(p.text[p.pos:], "/"):
+		sb := &strings.Builder{}
+		p.pos++
+		for p.pos < len(p.text) && p.text[p.pos] != '/' {
+			if p.text[p.pos] == '\\' && p.pos+1 < len(p.text) && p.text[p.pos+1] == '/' {
+				p.pos++
+			}
+			sb.WriteByte(p.text[p.pos])
+			p.pos++
+		}
+		if p.pos >= len(p.text) {
+			p.pos = start
+			return nil, p.errorf("unterminated regex")
+		}
+		p.pos++
+		flags := ""
+		for p.pos < len(p.text) && strings.ContainsRune("ims", rune(p.text[p.pos])) {
+			flags += string(p.text[p.pos])
+			p.pos++
+		}
+		expr = sb.String()
+		if flags != "" {
+			expr = "(?" + flags + ")" + expr
+		}
+	default:
+		return nil, p.expected("/regex/")
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("%s", err)
+	}
+	return re, nil
+}
+
+// equal compares numbers numerically and everything else by value. Strings
+// containing numbers equal these numbers, e.g. the values of logfmt messages.
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare compares numbers or strings. It returns false if a and b are not
+// comparable.
+func compare(a, b interface{}) (int, bool) {
+	na, aok := numeric(a)
+	nb, bok := numeric(b)
+	if aok && bok {
+		switch {
+		case na < nb:
+			return -1, true
+		case na > nb:
+			return 1, true
+		}
+		return 0, true
+	}
+	sa, aok := a.(string)
+	sb, bok := b.(string)
+	if aok && bok {
+		return strings.Compare(sa, sb), true
+	}
+	return 0, false
+}
+
+// numeric returns numbers and strings containing numbers as float64.
+func numeric(value interface{}) (float64, bool) {
+	if n, ok := number(value); ok {
+		return n, true
+	}
+	if s, ok := value.(string); ok {
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		// words like inf or nan are no numbers in log messages
+		return n, err == nil && !math.IsInf(n, 0) && !math.IsNaN(n)
+	}
+	return 0, false
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCondition(t *testing.T) {
+	json := testLog("gw-eks-int-1", `{"level": "error", "duration_ms": 750, "log": "upstream Timeout after 30s", "kubernetes": {"pod_name": "gw-1"}, "tags": ["a", "b"], "retry": false}`)
+	logfmt := testLog("gw-eks-int-1", `level=fatal duration_ms=120 log="connection refused"`)
+	text := testLog("gw-eks-int-1", `START RequestId: 1`)
+
+	for expr, expected := range map[string][]bool{
+		`level in ("error","fatal") && duration_ms > 500`:    {true, false, false},
+		`level in ("error", "fatal")`:                        {true, true, false},
+		`log =~ /timeout/i`:                                  {true, false, false},
+		`log =~ /timeout/`:                                   {false, false, false},
+		`log !~ /timeout/i`:                                  {false, true, false},
+		`log =~ "refused$"`:                                  {false, true, false},
+		`text =~ /^START/`:                                   {false, false, true},
+		`duration_ms >= 120 && duration_ms <= 750`:           {true, true, false},
+		`duration_ms < 500 || kubernetes.pod_name == "gw-1"`: {true, true, false},
+		`kubernetes.pod_name != "gw-1"`:                      {false, true, true},
+		`!(level == "error")`:                                {false, true, true},
+		`!retry && tags.1 == "b"`:                            {true, false, false},
+		`level == null`:                                      {false, false, true},
+		`kubernetes`:                                         {true, false, false},
+		`metadata.log-stream-name =~ /^gw-eks-int/ && metadata.timestamp > 1641135845000`: {true, true, true},
+		`metadata.message =~ /RequestId/`:                                                 {false, false, true},
+		`level > "d"`:                                                                     {true, true, false},
+		`duration_ms == "750"`:                                                            {true, false, false},
+	} {
+		c, err := NewCondition(expr)
+		assert.NoError(t, err, expr)
+		for i, log := range []Log{json, logfmt, text} {
+			match, err := c.Match(log)
+			assert.NoError(t, err)
+			assert.Equal(t, expected[i], match, "%s on event %d", expr, i)
+		}
+	}
+}
+
+func TestNewConditionErrors(t *testing.T) {
+	for text, expected := range map[string]string{
+		`level ==`:               "can't parse condition at 8: unexpected end",
+		`level in "error"`:       `can't parse condition at 9: expected ( but found "\""`,
+		`level in ("error"`:      "can't parse condition at 17: expected , or )",
+		`log =~ /timeout`:        "can't parse condition at 7: unterminated regex",
+		`log =~ /(/`:             "can't parse condition at 7: error parsing regexp: missing closing ): `(`",
+		`log =~ timeout`:         `can't parse condition at 7: expected /regex/ but found "t"`,
+		`(level == "error"`:      "can't parse condition at 17: expected )",
+		`level == "error" extra`: `can't parse condition at 17: unexpected "extra"`,
+		`level == "error`:        "can't parse condition at 9: unterminated string",
+	} {
+		_, err := NewCondition(text)
+		assert.EqualError(t, err, expected, text)
+	}
+}
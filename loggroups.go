@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+)
+
+const globChars = "*?["
+
+// resolveLogGroups returns the names of all log groups matching one of the patterns.
+// Patterns without glob characters are returned as they are. For glob patterns all
+// log groups starting with the part in front of the first glob character are listed
+// and matched using path.Match, so '*' doesn't match '/'.
+func resolveLogGroups(ctx context.Context, client cloudwatchlogs.DescribeLogGroupsAPIClient, patterns []string) ([]string, error) {
+	groups := []string{}
+	known := map[string]bool{}
+	add := func(group string) {
+		if !known[group] {
+			known[group] = true
+			groups = append(groups, group)
+		}
+	}
+
+	for _, pattern := range patterns {
+		idx := strings.IndexAny(pattern, globChars)
+		if idx < 0 {
+			add(pattern)
+			continue
+		}
+
+		input := &cloudwatchlogs.DescribeLogGroupsInput{}
+		if idx > 0 {
+			input.LogGroupNamePrefix = aws.String(pattern[:idx])
+		}
+		matched := false
+		paginator := cloudwatchlogs.NewDescribeLogGroupsPaginator(client, input)
+		for paginator.HasMorePages() {
+			out, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, group := range out.LogGroups {
+				if group.LogGroupName == nil {
+					continue
+				}
+				ok, err := path.Match(pattern, *group.LogGroupName)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					matched = true
+					add(*group.LogGroupName)
+				}
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no log group matches %s", pattern)
+		}
+	}
+	return groups, nil
+}
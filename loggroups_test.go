@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type describeGroupsClient []string
+
+func (c describeGroupsClient) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	out := &cloudwatchlogs.DescribeLogGroupsOutput{}
+	for _, name := range c {
+		if params.LogGroupNamePrefix == nil || strings.HasPrefix(name, *params.LogGroupNamePrefix) {
+			out.LogGroups = append(out.LogGroups, types.LogGroup{LogGroupName: aws.String(name)})
+		}
+	}
+	return out, nil
+}
+
+func TestResolveLogGroups(t *testing.T) {
+	client := describeGroupsClient{
+		"/aws/containerinsights/eks-prod/application",
+		"/aws/containerinsights/eks-prod/performance",
+		"/aws/containerinsights/eks-test/application",
+		"/aws/lambda/function",
+	}
+
+	t.Run("plain names", func(t *testing.T) {
+		groups, err := resolveLogGroups(context.Background(), client, []string{"a", "b", "a"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, groups)
+	})
+	t.Run("glob", func(t *testing.T) {
+		groups, err := resolveLogGroups(context.Background(), client, []string{"/aws/containerinsights/*/application", "/aws/lambda/function"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"/aws/containerinsights/eks-prod/application",
+			"/aws/containerinsights/eks-test/application",
+			"/aws/lambda/function",
+		}, groups)
+	})
+	t.Run("no match", func(t *testing.T) {
+		_, err := resolveLogGroups(context.Background(), client, []string{"/aws/ecs/*"})
+		assert.EqualError(t, err, "no log group matches /aws/ecs/*")
+	})
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := resolveLogGroups(context.Background(), client, []string{"/aws/[lambda"})
+		assert.Error(t, err)
+	})
+}
@@ -4,13 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/fs"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/smithy-go"
 	logger "github.com/sirupsen/logrus"
@@ -24,14 +24,46 @@ const (
 	loggroup        = "log-group"
 	starttime       = "start-time"
 	endtime         = "end-time"
+	timezone        = "timezone"
+	tz              = "tz"
+	timeFormat      = "time-format"
 	duration        = "duration"
 	filter          = "filter-pattern"
+	where           = "where"
 	filterFields    = "filter-fields"
+	project         = "project"
 	limit           = "limit"
+	maxEvents       = "max-events"
+	parallel        = "parallel"
+	resume          = "resume"
+	checkpointFile  = "checkpoint-file"
 	output          = "output"
+	outputFileFlag  = "output-file"
+	outputDir       = "output-dir"
+	ifExists        = "if-exists"
+	compress        = "compress"
+	splitBy         = "split-by"
+	rotateSize      = "rotate-size"
+	rotateInterval  = "rotate-interval"
+	rotateName      = "rotate-name"
 	outputFormat    = "output-format"
+	templateFlag    = "template"
+	colorFlag       = "color"
 	logstreamprefix = "logstream-prefix"
 	logstreamnames  = "logstream-names"
+	follow          = "follow"
+	followInterval  = "follow-interval"
+	namePrefix      = "name-prefix"
+	sortBy          = "sort-by"
+	reverse         = "reverse"
+	presetFlag      = "preset"
+	configFlag      = "config"
+	profile         = "profile"
+	region          = "region"
+	endpointURL     = "endpoint-url"
+	roleArn         = "role-arn"
+	externalID      = "external-id"
+	roleSessionName = "role-session-name"
 	versionFlag     = "version"
 	help            = "help"
 )
@@ -40,6 +72,12 @@ var version = "0.1-dev"
 
 var outputFile string
 
+// command is the first positional argument. It's empty when logs are fetched.
+var command string
+
+// queryString is the Insights query of the query command.
+var queryString string
+
 type ErrorMap map[string]error
 
 func (e ErrorMap) Error() string {
@@ -51,17 +89,49 @@ func (e ErrorMap) Error() string {
 }
 
 func init() {
-	flag.StringP(loggroup, "g", "", "The log group name to get logs from.")
-	flag.StringP(starttime, "s", "", "The start time of logs to get. Formt: 2006-01-02T15:04:05Z or 2006-01-02T15:04:05+07:00")
-	flag.StringP(endtime, "e", "", "The end time of logs to get. If not set we'll use today. Formt: 2006-01-02T15:04:05Z or 2006-01-02T15:04:05+07:00")
+	flag.StringSliceP(loggroup, "g", []string{}, "The log group name to get logs from. Can be given multiple times and can contain glob patterns like '/aws/containerinsights/*/application'. Logs of all groups are merged by timestamp.")
+	flag.StringP(starttime, "s", "", "The start time of logs to get. Format: 2006-01-02T15:04:05Z, 2006-01-02T15:04:05+07:00, 2006-01-02 15:04, 09:30, epoch seconds or milliseconds, now-2h, now-1d/d, today or yesterday 14:00")
+	flag.StringP(endtime, "e", "", "The end time of logs to get. If not set we'll use now. Supports the same formats as start-time.")
+	flag.String(timezone, "", "The timezone of start-time and end-time without offset, e.g. UTC or Europe/Berlin. Default is the local timezone.")
 	flag.StringP(duration, "d", "", "Duration(1w, 1d, 1h etc.) from today backwards of logs to get. If provided together with start-time, the duration will be added to the start-time to calculate the end-time.")
 	flag.StringP(filter, "f", "", "The filter pattern to filter logs.")
-	flag.StringSliceP(filterFields, "i", []string{}, "Select fields from the logstream which should be printed. Only works with logformat: yaml.")
+	flag.String(where, "", "Only print events matching this expression, e.g. 'level in (\"error\",\"fatal\") && duration_ms > 500' or 'log =~ /timeout/i'. It is evaluated on the parsed message and metadata after the filter-pattern was applied.")
+	flag.StringSliceP(filterFields, "i", []string{}, "Select fields from the logstream which should be printed. Only works with logformat: yaml, json, csv and tsv. For csv and tsv each field is a column. Messages which are neither JSON nor logfmt are available as field text. For yaml and json fields starting with ! are excluded, * matches any key and ** any number of keys.")
+	flag.String(project, "", "Print a new object for each event built from a jq like expression, e.g. '{pod: .kubernetes.pod_name, msg: .log, ts: .metadata.timestamp}'. Only works with logformat: yaml and json. Provides the functions length, keys, first, last, ascii_downcase, ascii_upcase, tostring, tonumber, split, join and time.")
 	flag.StringP(logstreamprefix, "p", "", "Filters the results to include only events from log streams that have names starting with this prefix.")
 	flag.StringSliceP(logstreamnames, "n", []string{}, "Filters the results to only logs from the log streams in this list.")
+	flag.Int(parallel, 1, "Split the time range into this number of shards which are fetched at the same time. The output is still ordered by timestamp. Later shards are fetched in advance into buffers of at most 256 MiB of messages together, plus up to one event per shard.")
 	flag.BoolP(output, "o", false, "Output logs to file")
-	flag.StringP(outputFormat, "t", "txt", "The format of the output file [txt, yaml]")
-	flag.Int32P(limit, "l", 10000, "The maximum number of events to return.")
+	flag.String(outputFileFlag, "", "The file to output logs to. Implies output. Use - for stdout. Can be a Go text/template using .Group, .Start, .End, .Now, .Format and .Ext. (default \""+defaultOutputFile+"\")")
+	flag.String(outputDir, "", "The directory to create output files in.")
+	flag.String(splitBy, "", "Write the logs to a file per log stream or message field [stream, field:<path>]. Implies output. The files are created in a directory named like the output file.")
+	flag.String(rotateSize, "", "Start a new output file once the current one reached this size, e.g. 100MB or 1GiB. Implies output.")
+	flag.String(rotateInterval, "", "Start a new output file for each interval of event time, e.g. 1h. Implies output.")
+	flag.String(rotateName, rotateNameNumber, "How rotated output files are named [number, time]. A manifest lists all files with their time range and number of events.")
+	flag.String(compress, "", "Compress the output [gzip, zstd]. The extension .gz or .zst is added to the output file. Compressed exports can't be resumed.")
+	flag.String(ifExists, ifExistsAppend, "What to do if the output file already exists [append, overwrite, fail].")
+	flag.Bool(resume, false, "Resume an export to file which was stopped using the checkpoint file. The query, output file and format are taken from the checkpoint.")
+	flag.String(checkpointFile, "lc-checkpoint.json", "The file to save the progress of exports to file in.")
+	flag.BoolP(follow, "F", false, "Keep polling for new logs after all existing logs were fetched. Stop with Ctrl-C.")
+	flag.String(followInterval, "5s", "The interval to poll for new logs in follow mode.")
+	flag.StringP(outputFormat, "t", "txt", "The format of the output file [txt, yaml, json, csv, tsv]")
+	flag.String(templateFlag, "", "A Go text/template to format each line of the txt output, e.g. '{{.Timestamp | time \"15:04:05\"}} {{.LogStreamName}} {{.Message.log}}'. Provides the functions time, truncate, pad, padLeft, color, json and default.")
+	flag.String(tz, "", "The timezone to print timestamps in, e.g. UTC, Local or Europe/Berlin. Default is the local timezone.")
+	flag.String(timeFormat, "", "The format of printed timestamps [rfc3339, rfc3339nano, epoch, kitchen] or a Go time layout like 2006-01-02 15:04:05. Default is rfc3339 for txt and epoch milliseconds for all other formats.")
+	flag.String(colorFlag, colorAuto, "Color the txt output [auto, always, never]. auto colors only if stdout is a terminal and NO_COLOR is not set.")
+	flag.Int32P(limit, "l", 10000, "The maximum number of events to return per request. Use max-events to limit the total number of events.")
+	flag.IntP(maxEvents, "m", 0, "The maximum number of events to print in total. Paging stops once this number is reached. 0 means no limit.")
+	flag.String(namePrefix, "", "Only list log groups or log streams with names starting with this prefix. Only used by the groups and streams commands.")
+	flag.String(sortBy, sortByName, "Sort log groups by [name, size, created] or log streams by [name, created, last-event]. Only used by the groups and streams commands.")
+	flag.Bool(reverse, false, "Reverse the sort order of the groups and streams commands.")
+	flag.String(presetFlag, "", "Use the settings of a named preset from the config file. Flags given on the command line override the preset.")
+	flag.String(configFlag, defaultConfigFile(), "The config file containing the presets.")
+	flag.String(profile, "", "The AWS profile to use from ~/.aws/config and ~/.aws/credentials.")
+	flag.String(region, "", "The AWS region to use.")
+	flag.String(endpointURL, "", "Override the CloudWatch Logs endpoint, e.g. for a local stand-in.")
+	flag.String(roleArn, "", "The ARN of a role to assume before getting logs.")
+	flag.String(externalID, "", "The external ID to use when assuming the role given by role-arn.")
+	flag.String(roleSessionName, "", "The session name to use when assuming the role given by role-arn.")
 	flag.BoolP(versionFlag, "v", false, "Print version information")
 	flag.BoolP(help, "?", false, "Print usage information")
 
@@ -77,20 +147,49 @@ https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/FilterAndPatternSyntax.
 
 Usage:
   lc [flags]
+  lc query <insights query> [flags]
+  lc groups [flags]
+  lc streams -g <log group> [flags]
 
 Preqrequisites:
   lc uses already provided credentials in ~/.aws/credentials also it uses the
   central configuration in ~/.aws/config! You can find out more about configuration
   options (e.g. retries etc.) at https://docs.aws.amazon.com/cli/latest/userguide/cli-configure-files.html
+  Use --profile, --region and --role-arn to select other credentials than the default ones.
 
 Examples:
   lc
   lc -g '/aws/containerinsights/eks-prod/application' -d 1h
+  lc -g '/aws/containerinsights/*/application' -g '/aws/lambda/my-function' -d 1h
   lc -g '/aws/containerinsights/eks-prod/application' -d 1h -p gw-eks-int
+  lc -g '/aws/containerinsights/eks-prod/application' -s 'yesterday 14:00' -e 'yesterday 15:30' --timezone Europe/Berlin
+  lc -g '/aws/containerinsights/eks-prod/application' -s now-1d/d -e now/d
+  lc -g '/aws/containerinsights/eks-prod/application' -d 1h --tz UTC --time-format '2006-01-02 15:04:05.000' -t yaml
   lc -g '/aws/containerinsights/eks-prod/application' -d 1h -p gw-eks-int -o
+  lc -g '/aws/containerinsights/eks-prod/application' -d 1d -p gw-eks-int -m 100
+  lc --preset gw-prod-errors -d 2h
+  lc -g '/aws/containerinsights/eks-prod/application' -d 1w --parallel 8 -o -t jsonl
+  lc -g '/aws/containerinsights/eks-prod/application' -d 1w -o -t jsonl --compress zstd
+  lc -g '/aws/containerinsights/eks-prod/application' -d 5m -F -t jsonl --rotate-size 100MB --rotate-interval 1h --rotate-name time
+  lc -g '/aws/containerinsights/eks-prod/application' -d 1h --split-by field:kubernetes.pod_name --output-dir incident-1234
+  lc -g '/aws/containerinsights/eks-prod/application' -d 1d -t yaml --output-dir exports --output-file '{{.Group}}-{{.Start.Format "20060102"}}.{{.Ext}}' --if-exists overwrite
+  lc --resume
+  lc --profile prod --region eu-central-1 --role-arn arn:aws:iam::123456789012:role/log-reader -g '/aws/lambda/my-function' -d 1h
+  lc -g '/aws/containerinsights/eks-prod/application' -d 5m -p gw-eks-int -F
   lc -g '/aws/containerinsights/eks-prod/application' -d 1h -p gw-eks-int -o -f 
   lc -g '/aws/containerinsights/eks-prod/application' -d 1h -p gw-eks-int -o -f '{($.kubernetes.namespace_name=ibm-api-connect-gw-int) && ($.log=*multistep*)}
   lc -g '/aws/containerinsights/eks-test/application' -d 1h -p gw-eks-int -t yaml -i log -i kubernetes.pod_name -i metadata.Timestamp'
+  lc -g '/aws/containerinsights/eks-test/application' -d 1h --template '{{.Timestamp | time "15:04:05"}} {{.LogStreamName | truncate 30 | pad 30}} {{.Message.log}}'
+  lc -g '/aws/containerinsights/eks-prod/application' -d 1h --color always | less -R
+  lc -g '/aws/containerinsights/eks-test/application' -d 1h -t json -i log -i kubernetes.pod_name | jq .
+  lc -g '/aws/containerinsights/eks-test/application' -d 1h -t json -i '!kubernetes.labels' -i '!kubernetes.annotations' -i '!**.trace_id'
+  lc -g '/aws/containerinsights/eks-test/application' -d 1h -t jsonl --project '{pod: .kubernetes.pod_name, msg: .log, ts: .metadata.timestamp}'
+  lc -g '/aws/containerinsights/eks-prod/application' -d 1h --where 'level in ("error","fatal") && duration_ms > 500'
+  lc -g '/aws/containerinsights/eks-prod/application' -d 1h --where 'log =~ /timeout/i'
+  lc query 'stats count() by bin(5m)' -g '/aws/containerinsights/eks-prod/application' -d 1h
+  lc groups --name-prefix /aws/containerinsights --sort-by size --reverse
+  lc streams -g '/aws/containerinsights/eks-prod/application' --name-prefix gw-eks-int --sort-by last-event
+  lc -g '/aws/containerinsights/eks-test/application' -d 1h -o -t csv -i metadata.Timestamp -i kubernetes.pod_name -i log
 
 Flags:`)
 
@@ -98,6 +197,8 @@ Flags:`)
 	}
 
 	flag.Parse()
+	command = flag.Arg(0)
+	queryString = flag.Arg(1)
 	err := viper.BindPFlags(flag.CommandLine)
 	CheckError(err, logger.Fatalf)
 	logger.SetLevel(logger.DebugLevel)
@@ -109,53 +210,113 @@ func main() {
 	} else if viper.GetBool(help) {
 		flag.Usage()
 	} else {
+		// exit after all deferred functions closed the output
+		exitCode := 0
+		defer func() {
+			if exitCode != 0 {
+				os.Exit(exitCode)
+			}
+		}()
+
+		if viper.GetString(presetFlag) != "" {
+			err := loadPreset(viper.GetString(configFlag), viper.GetString(presetFlag))
+			CheckError(err, logger.Fatalf)
+		}
 		err := validateFlags()
 		CheckError(err, logger.Fatalf)
 		filterLogEvents, err := parseFlags()
 		CheckError(err, logger.Fatalf)
 
-		cfg, err := config.LoadDefaultConfig(context.TODO())
-		CheckError(err, logger.Fatalf)
-		client := cloudwatchlogs.NewFromConfig(cfg)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-		paginator := cloudwatchlogs.NewFilterLogEventsPaginator(client, filterLogEvents)
+		cfg, err := loadAWSConfig(ctx)
+		CheckError(err, logger.Fatalf)
+		client := newClient(cfg)
 
-		var file *os.File
-		if viper.GetBool(output) {
-			file, err = os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_RDWR, fs.FileMode(0644))
+		var cp *checkpoint
+		if viper.GetBool(resume) {
+			cp, err = loadCheckpoint(viper.GetString(checkpointFile))
 			CheckError(err, logger.Fatalf)
-			defer file.Close()
+			outputFile = cp.OutputFile
+			viper.Set(output, true)
+			viper.Set(ifExists, ifExistsAppend)
+			cp.restore()
+			logger.Infof("resuming %s after %d events", outputFile, cp.Written)
 		}
+		// set after resuming, which restores the time format
+		err = setTimeFormat()
+		CheckError(err, logger.Fatalf)
 
-		for paginator.HasMorePages() {
-			logResults, err := paginator.NextPage(context.TODO())
-			if !CheckError(err, logger.Errorf) && logResults != nil {
-				for _, event := range logResults.Events {
-					log := internal.Log(event)
-					if viper.GetBool(output) {
-						switch e := strings.ToLower(viper.GetString(outputFormat)); e {
-						case "txt", "text":
-							_, err := log.PrintTxtFile(file)
-							CheckError(err, logger.Errorf)
-						case "yml", "yaml":
-							_, err := log.PrintYamlFile(file, viper.GetStringSlice(filterFields)...)
-							CheckError(err, logger.Errorf)
-						}
-					} else {
-						switch e := strings.ToLower(viper.GetString(outputFormat)); e {
-						case "txt", "text":
-							log.PrintOutTxt()
-						case "yml", "yaml":
-							err := log.PrintOutYml(viper.GetStringSlice(filterFields)...)
-							CheckError(err, logger.Errorf)
-						}
-					}
-				}
+		out, err := openOutput(cp != nil && cp.Written > 0)
+		CheckError(err, logger.Fatalf)
+		defer func() {
+			if CheckError(out.Close(), logger.Errorf) {
+				exitCode = 1
 			}
+		}()
+
+		// errors must not exit before the output is closed
+		if CheckError(runCommand(ctx, client, filterLogEvents, cp, out), logger.Errorf) {
+			exitCode = 1
 		}
 	}
 }
 
+// runCommand runs command and writes its results to out.
+func runCommand(ctx context.Context, client *cloudwatchlogs.Client, filterLogEvents *cloudwatchlogs.FilterLogEventsInput, cp *checkpoint, out logWriter) error {
+	var groups []string
+	if command != groupsCommand {
+		var err error
+		groups, err = resolveLogGroups(ctx, client, viper.GetStringSlice(loggroup))
+		if err != nil {
+			return err
+		}
+	}
+
+	switch command {
+	case groupsCommand:
+		logGroups, err := listLogGroups(ctx, client, viper.GetString(namePrefix))
+		if err != nil {
+			return err
+		}
+		if err := sortLogGroups(logGroups, viper.GetString(sortBy), viper.GetBool(reverse)); err != nil {
+			return err
+		}
+		for _, group := range logGroups {
+			out.write(internal.LogGroupRecord(group))
+		}
+	case streamsCommand:
+		for i := range groups {
+			logStreams, err := listLogStreams(ctx, client, groups[i], viper.GetString(namePrefix))
+			if err != nil {
+				return err
+			}
+			if err := sortLogStreams(logStreams, viper.GetString(sortBy), viper.GetBool(reverse)); err != nil {
+				return err
+			}
+			var group *string
+			if len(groups) > 1 {
+				group = &groups[i]
+			}
+			for _, stream := range logStreams {
+				out.write(internal.LogStreamRecord(stream, group))
+			}
+		}
+	case queryCommand:
+		results, err := runQuery(ctx, client, newStartQueryInput(queryString, groups, filterLogEvents))
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			out.write(result)
+		}
+	default:
+		return exportLogs(ctx, client, groups, filterLogEvents, cp, out)
+	}
+	return nil
+}
+
 func CheckError(err error, loggerFunc func(format string, args ...interface{})) (wasError bool) {
 	wasError = false
 
@@ -174,20 +335,138 @@ func CheckError(err error, loggerFunc func(format string, args ...interface{}))
 func validateFlags() error {
 	errs := ErrorMap{}
 
-	if viper.GetString(loggroup) == "" {
+	if len(viper.GetStringSlice(loggroup)) == 0 && command != groupsCommand && !viper.GetBool(resume) {
 		errs[loggroup] = fmt.Errorf("%s is a required flag", loggroup)
 	}
+	if _, err := loadTimezone(viper.GetString(timezone)); err != nil {
+		errs[timezone] = err
+	}
+	if _, err := loadTimezone(viper.GetString(tz)); err != nil {
+		errs[tz] = err
+	}
 	if viper.GetString(endtime) != "" && viper.GetString(duration) != "" {
 		errs[duration] = fmt.Errorf("%s and %s must not provided together", endtime, duration)
 	}
+	if viper.GetString(endtime) != "" && viper.GetBool(follow) {
+		errs[follow] = fmt.Errorf("%s and %s must not provided together", endtime, follow)
+	}
+	if x := viper.GetString(followInterval); x != "" && viper.GetBool(follow) {
+		// checked before fetching, so a typo doesn't stop follow mode after the export
+		if interval, err := str2duration.ParseDuration(x); err != nil {
+			errs[followInterval] = err
+		} else if interval <= 0 {
+			errs[followInterval] = fmt.Errorf("%s must be positive", followInterval)
+		}
+	}
+	if viper.IsSet(parallel) && viper.GetInt(parallel) < 1 {
+		errs[parallel] = fmt.Errorf("%s must be at least 1", parallel)
+	}
+	if viper.GetInt(maxEvents) < 0 {
+		errs[maxEvents] = fmt.Errorf("%s must not be negative", maxEvents)
+	}
+	if viper.GetString(roleArn) == "" && (viper.GetString(externalID) != "" || viper.GetString(roleSessionName) != "") {
+		errs[roleArn] = fmt.Errorf("%s and %s require %s", externalID, roleSessionName, roleArn)
+	}
 	if viper.GetString(outputFormat) != "" {
 		switch x := strings.ToLower(viper.GetString(outputFormat)); x {
-		case "txt", "text", "yaml", "yml":
+		case "txt", "text", "yaml", "yml", "json", "jsonl", "csv", "tsv":
 			break
 		default:
-			errs[outputFormat] = fmt.Errorf("%s given but expected [txt, yaml]", x)
+			errs[outputFormat] = fmt.Errorf("%s given but expected [txt, yaml, json, csv, tsv]", x)
+		}
+	}
+	if x := strings.ToLower(viper.GetString(outputFormat)); x == "csv" || x == "tsv" {
+		for _, field := range viper.GetStringSlice(filterFields) {
+			if strings.HasPrefix(field, "!") || strings.Contains(field, "*") {
+				errs[filterFields] = fmt.Errorf("%s given but %s %s requires plain field paths", field, outputFormat, x)
+			}
+		}
+	}
+	switch x := viper.GetString(ifExists); x {
+	case "", ifExistsAppend, ifExistsOverwrite, ifExistsFail:
+	default:
+		errs[ifExists] = fmt.Errorf("%s given but expected [%s, %s, %s]", x, ifExistsAppend, ifExistsOverwrite, ifExistsFail)
+	}
+	if x := viper.GetString(splitBy); x != "" {
+		if _, err := splitColumn(x); err != nil {
+			errs[splitBy] = err
+		} else if viper.GetString(outputFileFlag) == "-" {
+			errs[splitBy] = fmt.Errorf("%s can't be used with stdout", splitBy)
+		}
+	}
+	if x := viper.GetString(rotateSize); x != "" {
+		if _, err := parseSize(x); err != nil {
+			errs[rotateSize] = err
+		}
+	}
+	if x := viper.GetString(rotateInterval); x != "" {
+		if _, err := str2duration.ParseDuration(x); err != nil {
+			errs[rotateInterval] = err
+		}
+	}
+	if x := viper.GetString(rotateName); x != "" && x != rotateNameNumber && x != rotateNameTime {
+		errs[rotateName] = fmt.Errorf("%s given but expected [%s, %s]", x, rotateNameNumber, rotateNameTime)
+	}
+	if rotate() {
+		switch {
+		case viper.GetString(splitBy) != "":
+			errs[rotateSize] = fmt.Errorf("rotation and %s must not provided together", splitBy)
+		case viper.GetBool(resume):
+			errs[rotateSize] = fmt.Errorf("rotation and %s must not provided together", resume)
+		case viper.GetString(outputFileFlag) == "-":
+			errs[rotateSize] = errors.New("rotation can't be used with stdout")
+		}
+	}
+	switch x := viper.GetString(compress); x {
+	case "", compressGzip, compressZstd:
+	default:
+		errs[compress] = fmt.Errorf("%s given but expected [%s, %s]", x, compressGzip, compressZstd)
+	}
+	if viper.GetString(compress) != "" && viper.GetBool(resume) {
+		errs[compress] = fmt.Errorf("%s and %s must not provided together", compress, resume)
+	}
+	switch x := viper.GetString(colorFlag); x {
+	case "", colorAuto, colorAlways, colorNever:
+	default:
+		errs[colorFlag] = fmt.Errorf("%s given but expected [%s, %s, %s]", x, colorAuto, colorAlways, colorNever)
+	}
+	if viper.GetString(where) != "" {
+		if _, err := internal.NewCondition(viper.GetString(where)); err != nil {
+			errs[where] = err
 		}
 	}
+	if viper.GetString(project) != "" {
+		if x := strings.ToLower(viper.GetString(outputFormat)); x != "yaml" && x != "yml" && x != "json" && x != "jsonl" {
+			errs[project] = fmt.Errorf("%s can only be used with %s yaml or json", project, outputFormat)
+		} else if _, err := internal.NewProjection(viper.GetString(project)); err != nil {
+			errs[project] = err
+		}
+	}
+	if viper.GetString(templateFlag) != "" {
+		if x := strings.ToLower(viper.GetString(outputFormat)); x != "" && x != "txt" && x != "text" {
+			errs[templateFlag] = fmt.Errorf("%s can only be used with %s txt", templateFlag, outputFormat)
+		} else if _, err := internal.NewLineTemplate(viper.GetString(templateFlag)); err != nil {
+			errs[templateFlag] = err
+		}
+	}
+
+	switch command {
+	case "":
+	case queryCommand:
+		if queryString == "" {
+			errs[queryCommand] = fmt.Errorf("%s requires a query string", queryCommand)
+		}
+	case groupsCommand:
+		if x := viper.GetString(sortBy); x != sortByName && x != sortBySize && x != sortByCreated {
+			errs[sortBy] = fmt.Errorf("%s given but expected [%s, %s, %s]", x, sortByName, sortBySize, sortByCreated)
+		}
+	case streamsCommand:
+		if x := viper.GetString(sortBy); x != sortByName && x != sortByCreated && x != sortByLastEvent {
+			errs[sortBy] = fmt.Errorf("%s given but expected [%s, %s, %s]", x, sortByName, sortByCreated, sortByLastEvent)
+		}
+	default:
+		errs["command"] = fmt.Errorf("unknown command %s", command)
+	}
 
 	if len(errs) == 0 {
 		return nil
@@ -195,17 +474,40 @@ func validateFlags() error {
 	return errs
 }
 
+// setTimeFormat sets the timezone and format of printed timestamps.
+func setTimeFormat() error {
+	var loc *time.Location
+	if viper.GetString(tz) != "" {
+		var err error
+		if loc, err = loadTimezone(viper.GetString(tz)); err != nil {
+			return err
+		}
+	}
+	internal.SetTimeFormat(loc, viper.GetString(timeFormat))
+	return nil
+}
+
+// rotate returns true if output files are rotated.
+func rotate() bool {
+	return viper.GetString(rotateSize) != "" || viper.GetString(rotateInterval) != ""
+}
+
 func parseFlags() (*cloudwatchlogs.FilterLogEventsInput, error) {
 
 	var startTime, endTime time.Time
 	var dur time.Duration
 	var err error
 
-	endTime = time.Now()
+	now := time.Now()
+	endTime = now
+	loc, err := loadTimezone(viper.GetString(timezone))
+	if err != nil {
+		return nil, err
+	}
 
+	// LogGroupName is set for each log group returned by resolveLogGroups
 	filterLogEvents := &cloudwatchlogs.FilterLogEventsInput{
-		LogGroupName: aws.String(viper.GetString(loggroup)),
-		Limit:        aws.Int32(viper.GetInt32(limit)),
+		Limit: aws.Int32(viper.GetInt32(limit)),
 	}
 
 	if viper.GetString(filter) != "" {
@@ -228,17 +530,21 @@ func parseFlags() (*cloudwatchlogs.FilterLogEventsInput, error) {
 	}
 
 	if viper.GetString(starttime) != "" {
-		startTime, err = time.Parse(time.RFC3339, viper.GetString(starttime))
+		startTime, err = parseTime(viper.GetString(starttime), now, loc)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	if viper.GetString(endtime) != "" {
-		endTime, err = time.Parse(time.RFC3339, viper.GetString(endtime))
+		endTime, err = parseTime(viper.GetString(endtime), now, loc)
 		if err != nil {
 			return nil, err
 		}
+		// -s 09:00 -e 10:00 at 09:30 ends today, although 10:00 alone is yesterday
+		if timeOfDay.MatchString(strings.TrimSpace(viper.GetString(endtime))) && endTime.Before(startTime) {
+			endTime = endTime.AddDate(0, 0, 1)
+		}
 	}
 
 	zeroTime := time.Time{}
@@ -247,13 +553,26 @@ func parseFlags() (*cloudwatchlogs.FilterLogEventsInput, error) {
 			endTime = startTime.Add(dur)
 		}
 	} else {
-		startTime = time.Now().Add(dur * -1)
+		startTime = now.Add(dur * -1)
 	}
 	filterLogEvents.StartTime = aws.Int64(startTime.UnixMilli())
 
 	filterLogEvents.EndTime = aws.Int64(endTime.UnixMilli())
 
-	outputFile = fmt.Sprintf("logs%s-%d.txt", strings.ReplaceAll(viper.GetString(loggroup), "/", "-"), time.Now().Unix())
+	switch viper.GetString(outputFileFlag) {
+	case "":
+	case "-":
+		viper.Set(output, false)
+	default:
+		viper.Set(output, true)
+	}
+	if viper.GetString(splitBy) != "" || rotate() {
+		viper.Set(output, true)
+	}
+	outputFile, err = outputFileName(startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
 
 	return filterLogEvents, nil
 }
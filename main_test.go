@@ -54,6 +54,60 @@ func TestValidateFlags(t *testing.T) {
 		assert.NoError(t, err)
 		viper.Reset()
 	})
+	t.Run("Output format json", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(outputFormat, "jsonl")
+		err := validateFlags()
+		assert.NoError(t, err)
+		viper.Reset()
+	})
+	t.Run("Unknown output format", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(outputFormat, "xml")
+		err := validateFlags()
+		assert.Error(t, err)
+		assert.EqualError(t, err, fmt.Sprintf("%s:xml given but expected [txt, yaml, json, csv, tsv]\n", outputFormat))
+		viper.Reset()
+	})
+	t.Run("Filter fields with exclusion", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(outputFormat, "json")
+		viper.Set(filterFields, []string{"kubernetes.*", "!kubernetes.labels"})
+		assert.NoError(t, validateFlags())
+		viper.Set(outputFormat, "csv")
+		err := validateFlags()
+		assert.EqualError(t, err, fmt.Sprintf("%s:!kubernetes.labels given but %s csv requires plain field paths\n", filterFields, outputFormat))
+		viper.Reset()
+	})
+	t.Run("Where", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(where, `level in ("error","fatal") && duration_ms > 500`)
+		assert.NoError(t, validateFlags())
+		viper.Set(where, `log =~ /timeout`)
+		err := validateFlags()
+		assert.EqualError(t, err, fmt.Sprintf("%s:can't parse condition at 7: unterminated regex\n", where))
+		viper.Reset()
+	})
+	t.Run("Projection", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(outputFormat, "yaml")
+		viper.Set(project, "{pod: .kubernetes.pod_name, msg: .log}")
+		assert.NoError(t, validateFlags())
+
+		viper.Set(outputFormat, "txt")
+		err := validateFlags()
+		assert.EqualError(t, err, fmt.Sprintf("%s:%s can only be used with %s yaml or json\n", project, project, outputFormat))
+
+		viper.Set(outputFormat, "json")
+		viper.Set(project, "{pod: .kubernetes.pod_name")
+		err = validateFlags()
+		assert.EqualError(t, err, fmt.Sprintf("%s:can't parse projection at 26: expected , or }\n", project))
+
+		viper.Set(project, "{pod: .kubernetes.pod_name}")
+		viper.Set(filterFields, []string{"kubernetes.*", "!kubernetes.labels"})
+		assert.NoError(t, validateFlags())
+		viper.Reset()
+	})
 	t.Run("No loggroup", func(t *testing.T) {
 		viper.Set(starttime, "12345")
 		err := validateFlags()
@@ -70,6 +124,176 @@ func TestValidateFlags(t *testing.T) {
 		assert.EqualError(t, err, fmt.Sprintf("duration:%s and %s must not provided together\n", endtime, duration))
 		viper.Reset()
 	})
+	t.Run("Groups command without loggroup", func(t *testing.T) {
+		command = groupsCommand
+		t.Cleanup(func() { command = "" })
+		viper.Set(sortBy, sortBySize)
+		err := validateFlags()
+		assert.NoError(t, err)
+		viper.Reset()
+	})
+	t.Run("Streams command with invalid sort", func(t *testing.T) {
+		command = streamsCommand
+		t.Cleanup(func() { command = "" })
+		viper.Set(loggroup, "testgroup")
+		viper.Set(sortBy, sortBySize)
+		err := validateFlags()
+		assert.EqualError(t, err, fmt.Sprintf("%s:size given but expected [name, created, last-event]\n", sortBy))
+		viper.Reset()
+	})
+	t.Run("Query command without query string", func(t *testing.T) {
+		command = queryCommand
+		t.Cleanup(func() { command = "" })
+		viper.Set(loggroup, "testgroup")
+		err := validateFlags()
+		assert.EqualError(t, err, "query:query requires a query string\n")
+		queryString = "stats count()"
+		t.Cleanup(func() { queryString = "" })
+		assert.NoError(t, validateFlags())
+		viper.Reset()
+	})
+	t.Run("Unknown command", func(t *testing.T) {
+		command = "tail"
+		t.Cleanup(func() { command = "" })
+		viper.Set(loggroup, "testgroup")
+		err := validateFlags()
+		assert.EqualError(t, err, "command:unknown command tail\n")
+		viper.Reset()
+	})
+	t.Run("Parallel below 1", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(parallel, 0)
+		err := validateFlags()
+		assert.EqualError(t, err, fmt.Sprintf("%s:%s must be at least 1\n", parallel, parallel))
+		viper.Reset()
+	})
+	t.Run("Negative max-events", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(maxEvents, -1)
+		err := validateFlags()
+		assert.EqualError(t, err, fmt.Sprintf("%s:%s must not be negative\n", maxEvents, maxEvents))
+		viper.Reset()
+	})
+	t.Run("External ID without role", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(externalID, "external")
+		err := validateFlags()
+		assert.EqualError(t, err, fmt.Sprintf("%s:%s and %s require %s\n", roleArn, externalID, roleSessionName, roleArn))
+		viper.Reset()
+	})
+	t.Run("Unknown timezone", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(timezone, "Mars/Olympus")
+		err := validateFlags()
+		assert.ErrorContains(t, err, "timezone:unknown time zone Mars/Olympus")
+		viper.Reset()
+	})
+	t.Run("Unknown display timezone", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(tz, "Mars/Olympus")
+		err := validateFlags()
+		assert.ErrorContains(t, err, "tz:unknown time zone Mars/Olympus")
+		viper.Reset()
+	})
+	t.Run("Unknown if-exists mode", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(ifExists, "rename")
+		err := validateFlags()
+		assert.EqualError(t, err, "if-exists:rename given but expected [append, overwrite, fail]\n")
+		viper.Reset()
+	})
+	t.Run("Invalid split-by", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(splitBy, "pod")
+		err := validateFlags()
+		assert.EqualError(t, err, "split-by:pod given but expected [stream, field:<path>]\n")
+		viper.Reset()
+	})
+	t.Run("Split-by to stdout", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(splitBy, splitByStream)
+		viper.Set(outputFileFlag, "-")
+		err := validateFlags()
+		assert.EqualError(t, err, "split-by:split-by can't be used with stdout\n")
+		viper.Reset()
+	})
+	t.Run("Invalid rotation", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(rotateSize, "big")
+		viper.Set(rotateInterval, "often")
+		viper.Set(rotateName, "random")
+		err := validateFlags()
+		assert.ErrorContains(t, err, "rotate-size:invalid size big\n")
+		assert.ErrorContains(t, err, "rotate-interval:")
+		assert.ErrorContains(t, err, "rotate-name:random given but expected [number, time]\n")
+		viper.Reset()
+	})
+	t.Run("Rotation and split-by", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(rotateInterval, "1h")
+		viper.Set(splitBy, splitByStream)
+		err := validateFlags()
+		assert.EqualError(t, err, "rotate-size:rotation and split-by must not provided together\n")
+		viper.Reset()
+	})
+	t.Run("Unknown compression", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(compress, "bzip2")
+		err := validateFlags()
+		assert.EqualError(t, err, "compress:bzip2 given but expected [gzip, zstd]\n")
+		viper.Reset()
+	})
+	t.Run("Compression and resume", func(t *testing.T) {
+		viper.Set(compress, compressGzip)
+		viper.Set(resume, true)
+		err := validateFlags()
+		assert.EqualError(t, err, "compress:compress and resume must not provided together\n")
+		viper.Reset()
+	})
+	t.Run("Unknown color mode", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(colorFlag, "sometimes")
+		err := validateFlags()
+		assert.EqualError(t, err, "color:sometimes given but expected [auto, always, never]\n")
+		viper.Reset()
+	})
+	t.Run("Template with yaml output", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(outputFormat, "yaml")
+		viper.Set(templateFlag, "{{.EventId}}")
+		err := validateFlags()
+		assert.EqualError(t, err, "template:template can only be used with output-format txt\n")
+		viper.Reset()
+	})
+	t.Run("Invalid template", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(templateFlag, "{{.EventId")
+		err := validateFlags()
+		assert.ErrorContains(t, err, "unclosed action")
+		viper.Reset()
+	})
+	t.Run("Endtime and follow at the same time", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(endtime, "12345")
+		viper.Set(follow, true)
+		err := validateFlags()
+		assert.Error(t, err)
+		assert.EqualError(t, err, fmt.Sprintf("follow:%s and %s must not provided together\n", endtime, follow))
+		viper.Reset()
+	})
+	t.Run("Invalid follow interval", func(t *testing.T) {
+		viper.Set(loggroup, "testgroup")
+		viper.Set(follow, true)
+		viper.Set(followInterval, "5x")
+		err := validateFlags()
+		assert.ErrorContains(t, err, followInterval+":")
+		viper.Set(followInterval, "0s")
+		err = validateFlags()
+		assert.EqualError(t, err, fmt.Sprintf("%s:%s must be positive\n", followInterval, followInterval))
+		viper.Set(followInterval, "10s")
+		assert.NoError(t, validateFlags())
+		viper.Reset()
+	})
 	t.Cleanup(viper.Reset)
 }
 
@@ -84,7 +308,8 @@ func TestParseFlags(t *testing.T) {
 		t.Cleanup(flagDefaults)
 		filterLogsInput, err := parseFlags()
 		assert.NoError(t, err)
-		assert.Equal(t, "unittest", *filterLogsInput.LogGroupName)
+		assert.Nil(t, filterLogsInput.LogGroupName)
+		assert.Equal(t, []string{"unittest"}, viper.GetStringSlice(loggroup))
 		assert.Equal(t, int32(10000), *filterLogsInput.Limit)
 		viper.Reset()
 	})
@@ -162,4 +387,53 @@ func TestParseFlags(t *testing.T) {
 		assert.Equal(t, expectedEnd.UnixMilli(), *filterLogsInput.EndTime)
 		viper.Reset()
 	})
+	t.Run("With output file", func(t *testing.T) {
+		t.Cleanup(flagDefaults)
+		viper.Set(outputFileFlag, "logs.{{.Ext}}")
+		_, err := parseFlags()
+		assert.NoError(t, err)
+		assert.True(t, viper.GetBool(output))
+		assert.Equal(t, "logs.txt", outputFile)
+		viper.Reset()
+	})
+	t.Run("With output file stdout", func(t *testing.T) {
+		t.Cleanup(flagDefaults)
+		viper.Set(output, true)
+		viper.Set(outputFileFlag, "-")
+		_, err := parseFlags()
+		assert.NoError(t, err)
+		assert.False(t, viper.GetBool(output))
+		viper.Reset()
+	})
+	t.Run("With times of day around now", func(t *testing.T) {
+		t.Cleanup(flagDefaults)
+		now := time.Now().UTC()
+		viper.Set(starttime, now.Add(-time.Minute).Format("15:04:05"))
+		viper.Set(endtime, now.Add(time.Minute).Format("15:04:05"))
+		viper.Set(timezone, "UTC")
+		filterLogsInput, err := parseFlags()
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, *filterLogsInput.StartTime, now.UnixMilli())
+		assert.Equal(t, (2 * time.Minute).Milliseconds(), *filterLogsInput.EndTime-*filterLogsInput.StartTime)
+		viper.Reset()
+	})
+	t.Run("With relative times and timezone", func(t *testing.T) {
+		t.Cleanup(flagDefaults)
+		viper.Set(starttime, "2022-01-02 15:04")
+		viper.Set(endtime, "now/d")
+		viper.Set(timezone, "UTC")
+		filterLogsInput, err := parseFlags()
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2022, 1, 2, 15, 4, 0, 0, time.UTC).UnixMilli(), *filterLogsInput.StartTime)
+		now := time.Now().UTC()
+		assert.Equal(t, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).UnixMilli(), *filterLogsInput.EndTime)
+		viper.Reset()
+	})
+	t.Run("With invalid start time", func(t *testing.T) {
+		t.Cleanup(flagDefaults)
+		viper.Set(starttime, "last week")
+		_, err := parseFlags()
+		assert.EqualError(t, err, "can't parse time last week")
+		viper.Reset()
+	})
 }
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/steffakasid/lc/internal"
+	"github.com/xhit/go-str2duration/v2"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+const (
+	ifExistsAppend    = "append"
+	ifExistsOverwrite = "overwrite"
+	ifExistsFail      = "fail"
+)
+
+const (
+	compressGzip = "gzip"
+	compressZstd = "zstd"
+)
+
+const defaultOutputFile = "logs{{.Group}}-{{.Now.Unix}}.{{.Ext}}"
+
+// fileNameReplacer replaces characters which aren't usable in file names.
+var fileNameReplacer = strings.NewReplacer("/", "-", "\\", "-", "*", "_", "?", "_", "[", "_", "]", "_")
+
+// outputFileData is available in output-file templates.
+type outputFileData struct {
+	// Group contains the log group names joined by + usable in file names.
+	Group           string
+	Start, End, Now time.Time
+	Format          string
+	// Ext is the file extension of the output format.
+	Ext string
+}
+
+// outputFileName returns the name of the output file for logs from start to end
+// using the output-file template and output-dir.
+func outputFileName(start, end time.Time) (string, error) {
+	text := viper.GetString(outputFileFlag)
+	if text == "" || text == "-" {
+		text = defaultOutputFile
+	}
+	tmpl, err := template.New(outputFileFlag).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	format := strings.ToLower(viper.GetString(outputFormat))
+	data := outputFileData{
+		Group:  fileNameReplacer.Replace(strings.Join(viper.GetStringSlice(loggroup), "+")),
+		Start:  start,
+		End:    end,
+		Now:    time.Now(),
+		Format: format,
+		Ext:    fileExtension(format),
+	}
+	sb := &strings.Builder{}
+	if err := tmpl.Execute(sb, data); err != nil {
+		return "", err
+	}
+	name := sb.String()
+	if ext := compressExtension(viper.GetString(compress)); !strings.HasSuffix(name, ext) {
+		name += ext
+	}
+	return filepath.Join(viper.GetString(outputDir), name), nil
+}
+
+func compressExtension(compression string) string {
+	switch compression {
+	case compressGzip:
+		return ".gz"
+	case compressZstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// compressor is a writer which compresses everything written to it.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// newCompressor returns a compressor writing to w or nil if compression is empty.
+func newCompressor(compression string, w io.Writer) (compressor, error) {
+	switch compression {
+	case "":
+		return nil, nil
+	case compressGzip:
+		return gzip.NewWriter(w), nil
+	case compressZstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("unknown compression %s", compression)
+}
+
+// outputExtension returns the extension of output files including the
+// compression, e.g. .jsonl.gz.
+func outputExtension() string {
+	return "." + fileExtension(strings.ToLower(viper.GetString(outputFormat))) + compressExtension(viper.GetString(compress))
+}
+
+func fileExtension(format string) string {
+	switch format {
+	case "yaml", "yml":
+		return "yaml"
+	case "json", "jsonl", "csv", "tsv":
+		return format
+	}
+	return "txt"
+}
+
+// openFlags returns the flags to open the output file with for the if-exists mode.
+func openFlags(mode string) int {
+	switch mode {
+	case ifExistsOverwrite:
+		return os.O_TRUNC | os.O_CREATE | os.O_WRONLY
+	case ifExistsFail:
+		return os.O_EXCL | os.O_CREATE | os.O_WRONLY
+	}
+	return os.O_APPEND | os.O_CREATE | os.O_RDWR
+}
+
+// logOutput writes everything lc prints either to stdout or, if output is set, to
+// the output file using the selected output format.
+type logOutput struct {
+	file *os.File
+	// compressor compresses the output to the file or stdout if set
+	compressor compressor
+	csvWriter  *internal.CsvWriter
+	// template formats the txt output if set
+	template *internal.LineTemplate
+	// color is true if the txt output is colored
+	color bool
+	// projection builds the yaml and json output if set
+	projection *internal.Projection
+	// counter counts the bytes written before they are compressed
+	counter *countingWriter
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// logWriter writes everything lc prints. It's implemented by logOutput and
+// splitOutput.
+type logWriter interface {
+	write(log internal.Printable)
+	// flush writes buffered output.
+	flush()
+	// Close closes the output. Errors of write, which only logs them, are returned
+	// again if events were dropped, so the export doesn't succeed without them.
+	Close() error
+	// resumable returns true if an export to this writer can be resumed.
+	resumable() bool
+}
+
+// openOutput opens the output file if output is set, the split output if split-by
+// is set or the rotating output if rotate-size or rotate-interval is set.
+// skipHeader prevents writing a csv header to the output file again when appending
+// to a resumed export. Split files decide this per file.
+func openOutput(skipHeader bool) (logWriter, error) {
+	if viper.GetString(splitBy) != "" {
+		return newSplitOutput(outputFile, viper.GetString(splitBy))
+	}
+	if rotate() {
+		var size int64
+		var interval time.Duration
+		var err error
+		if viper.GetString(rotateSize) != "" {
+			if size, err = parseSize(viper.GetString(rotateSize)); err != nil {
+				return nil, err
+			}
+		}
+		if viper.GetString(rotateInterval) != "" {
+			if interval, err = str2duration.ParseDuration(viper.GetString(rotateInterval)); err != nil {
+				return nil, err
+			}
+		}
+		return newRotatingOutput(outputFile, size, interval, viper.GetString(rotateName)), nil
+	}
+	if viper.GetBool(output) {
+		return newLogOutput(outputFile, viper.GetString(ifExists), skipHeader)
+	}
+	return newLogOutput("", viper.GetString(ifExists), skipHeader)
+}
+
+// newLogOutput opens the file name using the if-exists mode or writes to stdout if
+// name is empty. The csv header is also skipped when appending to a file which
+// isn't empty.
+func newLogOutput(name, mode string, skipHeader bool) (*logOutput, error) {
+	out := &logOutput{}
+	if name != "" {
+		if dir := filepath.Dir(name); dir != "." {
+			if err := os.MkdirAll(dir, fs.FileMode(0755)); err != nil {
+				return nil, err
+			}
+		}
+		file, err := os.OpenFile(name, openFlags(mode), fs.FileMode(0644))
+		if errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("%s already exists, use --%s %s or %s", name, ifExists, ifExistsAppend, ifExistsOverwrite)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out.file = file
+		if info, err := file.Stat(); err == nil && info.Size() > 0 {
+			skipHeader = true
+		}
+	}
+	var err error
+	out.compressor, err = newCompressor(viper.GetString(compress), out.target())
+	if err != nil {
+		out.Close()
+		return nil, err
+	}
+	out.color = useColor(viper.GetString(colorFlag), out.writer())
+	// set after checking for a terminal, which needs the file itself
+	out.counter = &countingWriter{w: out.writer()}
+
+	if viper.GetString(templateFlag) != "" {
+		tmpl, err := internal.NewLineTemplate(viper.GetString(templateFlag))
+		if err != nil {
+			return nil, err
+		}
+		out.template = tmpl
+		out.template.SetColor(out.color)
+	}
+
+	if viper.GetString(project) != "" {
+		projection, err := internal.NewProjection(viper.GetString(project))
+		if err != nil {
+			return nil, err
+		}
+		out.projection = projection
+		out.projection.SetFilter(viper.GetStringSlice(filterFields)...)
+	}
+
+	if e := strings.ToLower(viper.GetString(outputFormat)); e == "csv" || e == "tsv" {
+		comma := ','
+		if e == "tsv" {
+			comma = '\t'
+		}
+		out.csvWriter = internal.NewCsvWriter(out.writer(), comma, viper.GetStringSlice(filterFields)...)
+		if skipHeader {
+			out.csvWriter.SkipHeader()
+		}
+	}
+	return out, nil
+}
+
+func (o *logOutput) write(log internal.Printable) {
+	if t, ok := log.(internal.Templatable); ok && o.template != nil && o.isTxt() {
+		CheckError(o.template.Execute(o.writer(), t), logger.Errorf)
+		return
+	}
+	if c, ok := log.(internal.Colorable); ok && o.color && o.isTxt() {
+		_, err := io.WriteString(o.writer(), c.ColoredLine())
+		CheckError(err, logger.Errorf)
+		return
+	}
+	if p, ok := log.(internal.Projectable); ok && o.projection != nil {
+		switch e := strings.ToLower(viper.GetString(outputFormat)); e {
+		case "yml", "yaml":
+			CheckError(o.projection.WriteYaml(o.writer(), p), logger.Errorf)
+			return
+		case "json", "jsonl":
+			CheckError(o.projection.WriteJson(o.writer(), p), logger.Errorf)
+			return
+		}
+	}
+	if o.file != nil || o.compressor != nil {
+		switch e := strings.ToLower(viper.GetString(outputFormat)); e {
+		case "txt", "text":
+			_, err := log.PrintTxtFile(o.writer())
+			CheckError(err, logger.Errorf)
+		case "yml", "yaml":
+			_, err := log.PrintYamlFile(o.writer(), viper.GetStringSlice(filterFields)...)
+			CheckError(err, logger.Errorf)
+		case "json", "jsonl":
+			_, err := log.PrintJsonFile(o.writer(), viper.GetStringSlice(filterFields)...)
+			CheckError(err, logger.Errorf)
+		case "csv", "tsv":
+			err := o.csvWriter.Write(log)
+			CheckError(err, logger.Errorf)
+		}
+	} else {
+		switch e := strings.ToLower(viper.GetString(outputFormat)); e {
+		case "txt", "text":
+			log.PrintOutTxt()
+		case "yml", "yaml":
+			err := log.PrintOutYml(viper.GetStringSlice(filterFields)...)
+			CheckError(err, logger.Errorf)
+		case "json", "jsonl":
+			err := log.PrintOutJson(viper.GetStringSlice(filterFields)...)
+			CheckError(err, logger.Errorf)
+		case "csv", "tsv":
+			err := o.csvWriter.Write(log)
+			CheckError(err, logger.Errorf)
+		}
+	}
+}
+
+func (o *logOutput) resumable() bool {
+	// compressed streams can't be continued after they were cut off
+	return o.file != nil && o.compressor == nil
+}
+
+// writer returns the writer all output is written to.
+func (o *logOutput) writer() io.Writer {
+	if o.counter != nil {
+		return o.counter
+	}
+	if o.compressor != nil {
+		return o.compressor
+	}
+	return o.target()
+}
+
+// target returns the output file or stdout.
+func (o *logOutput) target() io.Writer {
+	if o.file != nil {
+		return o.file
+	}
+	return os.Stdout
+}
+
+// useColor returns whether output to w is colored in the given color mode. In auto
+// mode only terminals are colored, unless NO_COLOR is set.
+func useColor(mode string, w io.Writer) bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (o *logOutput) isTxt() bool {
+	e := strings.ToLower(viper.GetString(outputFormat))
+	return e == "txt" || e == "text"
+}
+
+// written returns the number of bytes written so far, before compression. Rows
+// buffered by the csv writer are flushed to count them.
+func (o *logOutput) written() int64 {
+	if o.csvWriter != nil {
+		CheckError(o.csvWriter.Flush(), logger.Errorf)
+	}
+	return o.counter.n
+}
+
+// flush writes buffered output.
+func (o *logOutput) flush() {
+	if o.csvWriter != nil {
+		CheckError(o.csvWriter.Flush(), logger.Errorf)
+	}
+	if o.compressor != nil {
+		CheckError(o.compressor.Flush(), logger.Errorf)
+	}
+}
+
+// Close flushes all output and closes the compressor and the output file.
+func (o *logOutput) Close() error {
+	if o.csvWriter != nil {
+		CheckError(o.csvWriter.Flush(), logger.Errorf)
+	}
+	var errs []error
+	if o.compressor != nil {
+		errs = append(errs, o.compressor.Close())
+	}
+	if o.file != nil {
+		errs = append(errs, o.file.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/klauspost/compress/zstd"
+	"github.com/spf13/viper"
+	"github.com/steffakasid/lc/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogOutput(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	log := internal.Log{FilteredLogEvent: types.FilteredLogEvent{
+		EventId:   aws.String("1"),
+		Timestamp: aws.Int64(1000),
+		Message:   aws.String("{\"log\": \"message\"}"),
+	}}
+
+	t.Run("csv file", func(t *testing.T) {
+		outputFile = path.Join(t.TempDir(), "logs.csv")
+		viper.Set(output, true)
+		viper.Set(outputFormat, "csv")
+		viper.Set(filterFields, []string{"log"})
+
+		out, err := openOutput(false)
+		assert.NoError(t, err)
+		out.write(log)
+		assert.NoError(t, out.Close())
+
+		out, err = openOutput(true)
+		assert.NoError(t, err)
+		out.write(log)
+		assert.NoError(t, out.Close())
+
+		bt, err := os.ReadFile(outputFile)
+		assert.NoError(t, err)
+		assert.Equal(t, "log\nmessage\nmessage\n", string(bt))
+		viper.Reset()
+	})
+	t.Run("template", func(t *testing.T) {
+		outputFile = path.Join(t.TempDir(), "logs.txt")
+		viper.Set(output, true)
+		viper.Set(outputFormat, "txt")
+		viper.Set(templateFlag, "{{.EventId}} {{.Message.log}}")
+
+		out, err := openOutput(false)
+		assert.NoError(t, err)
+		out.write(log)
+		assert.NoError(t, out.Close())
+
+		bt, err := os.ReadFile(outputFile)
+		assert.NoError(t, err)
+		assert.Equal(t, "1 message\n", string(bt))
+		viper.Reset()
+	})
+	t.Run("projection", func(t *testing.T) {
+		outputFile = path.Join(t.TempDir(), "logs.jsonl")
+		viper.Set(output, true)
+		viper.Set(outputFormat, "jsonl")
+		viper.Set(project, "{id: .metadata.event-id, msg: .log}")
+
+		out, err := openOutput(false)
+		assert.NoError(t, err)
+		out.write(log)
+		out.write(internal.Record{{Field: aws.String("count()"), Value: aws.String("42")}})
+		assert.NoError(t, out.Close())
+
+		bt, err := os.ReadFile(outputFile)
+		assert.NoError(t, err)
+		assert.Equal(t, "{\"id\":\"1\",\"msg\":\"message\"}\n{\"id\":null,\"msg\":null}\n", string(bt))
+		viper.Reset()
+	})
+	t.Run("color always", func(t *testing.T) {
+		outputFile = path.Join(t.TempDir(), "logs.txt")
+		viper.Set(output, true)
+		viper.Set(outputFormat, "txt")
+		viper.Set(colorFlag, colorAlways)
+
+		out, err := openOutput(false)
+		assert.NoError(t, err)
+		out.write(log)
+		assert.NoError(t, out.Close())
+
+		bt, err := os.ReadFile(outputFile)
+		assert.NoError(t, err)
+		assert.Equal(t, log.ColoredLine(), string(bt))
+		viper.Reset()
+	})
+	t.Run("stdout", func(t *testing.T) {
+		viper.Set(outputFormat, "txt")
+		out, err := openOutput(false)
+		assert.NoError(t, err)
+		assert.False(t, out.resumable())
+		out.write(log)
+		assert.NoError(t, out.Close())
+		viper.Reset()
+	})
+	t.Run("compressed", func(t *testing.T) {
+		for _, compression := range []string{compressGzip, compressZstd} {
+			t.Run(compression, func(t *testing.T) {
+				outputFile = path.Join(t.TempDir(), "logs.jsonl")
+				viper.Set(output, true)
+				viper.Set(outputFormat, "jsonl")
+				viper.Set(compress, compression)
+
+				// appending creates a second stream which is read after the first
+				for i := 0; i < 2; i++ {
+					out, err := openOutput(false)
+					assert.NoError(t, err)
+					out.write(log)
+					out.flush()
+					assert.NoError(t, out.Close())
+				}
+
+				assert.Equal(t, "{\"event-id\":\"1\",\"timestamp\":1000,\"message\":{\"log\":\"message\"}}\n{\"event-id\":\"1\",\"timestamp\":1000,\"message\":{\"log\":\"message\"}}\n", decompress(t, compression, outputFile))
+				viper.Reset()
+			})
+		}
+	})
+	t.Run("creates directories", func(t *testing.T) {
+		outputFile = path.Join(t.TempDir(), "missing", "logs.txt")
+		viper.Set(output, true)
+		out, err := openOutput(false)
+		assert.NoError(t, err)
+		assert.NoError(t, out.Close())
+		assert.FileExists(t, outputFile)
+		viper.Reset()
+	})
+	t.Run("if exists", func(t *testing.T) {
+		outputFile = path.Join(t.TempDir(), "logs.txt")
+		assert.NoError(t, os.WriteFile(outputFile, []byte("old\n"), fs.FileMode(0644)))
+		viper.Set(output, true)
+		viper.Set(outputFormat, "txt")
+
+		viper.Set(ifExists, ifExistsFail)
+		_, err := openOutput(false)
+		assert.EqualError(t, err, outputFile+" already exists, use --if-exists append or overwrite")
+
+		viper.Set(ifExists, ifExistsAppend)
+		out, err := openOutput(false)
+		assert.NoError(t, err)
+		out.write(log)
+		assert.NoError(t, out.Close())
+		bt, err := os.ReadFile(outputFile)
+		assert.NoError(t, err)
+		assert.Equal(t, "old\n"+log.FormatedLine(), string(bt))
+
+		viper.Set(ifExists, ifExistsOverwrite)
+		out, err = openOutput(false)
+		assert.NoError(t, err)
+		out.write(log)
+		assert.NoError(t, out.Close())
+		bt, err = os.ReadFile(outputFile)
+		assert.NoError(t, err)
+		assert.Equal(t, log.FormatedLine(), string(bt))
+		viper.Reset()
+	})
+}
+
+func decompress(t *testing.T, compression, name string) string {
+	file, err := os.Open(name)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var r io.Reader
+	switch compression {
+	case compressGzip:
+		r, err = gzip.NewReader(file)
+	case compressZstd:
+		r, err = zstd.NewReader(file)
+	}
+	assert.NoError(t, err)
+	bt, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(bt)
+}
+
+func TestOutputFileName(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	start := time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	t.Run("default", func(t *testing.T) {
+		viper.Set(loggroup, []string{"/aws/lambda/a", "/aws/*/b"})
+		viper.Set(outputFormat, "yml")
+		name, err := outputFileName(start, end)
+		assert.NoError(t, err)
+		assert.Regexp(t, `^logs-aws-lambda-a\+-aws-_-b-\d+\.yaml$`, name)
+		viper.Reset()
+	})
+	t.Run("template and directory", func(t *testing.T) {
+		viper.Set(loggroup, []string{"/aws/lambda/a"})
+		viper.Set(outputFormat, "jsonl")
+		viper.Set(outputDir, "exports")
+		viper.Set(outputFileFlag, `{{.Start.Format "20060102T1504"}}-{{.End.Format "1504"}}{{.Group}}.{{.Format}}`)
+		name, err := outputFileName(start, end)
+		assert.NoError(t, err)
+		assert.Equal(t, path.Join("exports", "20220102T1504-1604-aws-lambda-a.jsonl"), name)
+		viper.Reset()
+	})
+	t.Run("compressed", func(t *testing.T) {
+		viper.Set(outputFormat, "csv")
+		viper.Set(compress, compressZstd)
+		viper.Set(outputFileFlag, "logs.{{.Ext}}")
+		name, err := outputFileName(start, end)
+		assert.NoError(t, err)
+		assert.Equal(t, "logs.csv.zst", name)
+
+		viper.Set(compress, compressGzip)
+		viper.Set(outputFileFlag, "logs.gz")
+		name, err = outputFileName(start, end)
+		assert.NoError(t, err)
+		assert.Equal(t, "logs.gz", name)
+		viper.Reset()
+	})
+	t.Run("invalid template", func(t *testing.T) {
+		viper.Set(outputFileFlag, "{{.Group")
+		_, err := outputFileName(start, end)
+		assert.Error(t, err)
+		viper.Reset()
+	})
+}
+
+func TestUseColor(t *testing.T) {
+	file, err := os.Create(path.Join(t.TempDir(), "logs.txt"))
+	assert.NoError(t, err)
+	defer file.Close()
+
+	assert.True(t, useColor(colorAlways, file))
+	assert.False(t, useColor(colorNever, os.Stdout))
+	assert.False(t, useColor(colorAuto, file))
+
+	t.Setenv("NO_COLOR", "1")
+	assert.False(t, useColor(colorAuto, os.Stdout))
+}
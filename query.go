@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	logger "github.com/sirupsen/logrus"
+	"github.com/steffakasid/lc/internal"
+)
+
+const queryCommand = "query"
+
+// queryPollInterval is the time to wait between two GetQueryResults calls.
+var queryPollInterval = time.Second
+
+// insightsAPIClient is the part of the CloudWatch Logs client needed to run
+// Logs Insights queries.
+type insightsAPIClient interface {
+	StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error)
+	GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error)
+	StopQuery(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error)
+}
+
+// newStartQueryInput converts the parsed flags into a StartQueryInput. Insights
+// expects start and end time in seconds instead of milliseconds.
+func newStartQueryInput(queryString string, groups []string, filterLogEvents *cloudwatchlogs.FilterLogEventsInput) *cloudwatchlogs.StartQueryInput {
+	return &cloudwatchlogs.StartQueryInput{
+		QueryString:   aws.String(queryString),
+		LogGroupNames: groups,
+		StartTime:     aws.Int64(*filterLogEvents.StartTime / 1000),
+		EndTime:       aws.Int64(*filterLogEvents.EndTime / 1000),
+		Limit:         filterLogEvents.Limit,
+	}
+}
+
+// runQuery starts the query and polls the results until the query is finished.
+// If ctx is cancelled the query is stopped.
+func runQuery(ctx context.Context, client insightsAPIClient, input *cloudwatchlogs.StartQueryInput) ([]internal.Record, error) {
+	started, err := client.StartQuery(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			_, err := client.StopQuery(context.Background(), &cloudwatchlogs.StopQueryInput{QueryId: started.QueryId})
+			CheckError(err, logger.Errorf)
+			return nil, ctx.Err()
+		case <-time.After(queryPollInterval):
+		}
+
+		out, err := client.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{QueryId: started.QueryId})
+		if err != nil {
+			return nil, err
+		}
+
+		switch out.Status {
+		case types.QueryStatusComplete:
+			results := make([]internal.Record, len(out.Results))
+			for i, row := range out.Results {
+				results[i] = internal.Record(row)
+			}
+			return results, nil
+		case types.QueryStatusScheduled, types.QueryStatusRunning:
+			continue
+		default:
+			return nil, fmt.Errorf("query %s finished with status %s", *started.QueryId, out.Status)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeInsightsClient struct {
+	statuses []types.QueryStatus
+	results  [][]types.ResultField
+	startErr error
+	polls    int
+	stopped  bool
+}
+
+func (f *fakeInsightsClient) StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	if f.startErr != nil {
+		return nil, f.startErr
+	}
+	return &cloudwatchlogs.StartQueryOutput{QueryId: aws.String("query-1")}, nil
+}
+
+func (f *fakeInsightsClient) GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	status := f.statuses[f.polls]
+	f.polls++
+	out := &cloudwatchlogs.GetQueryResultsOutput{Status: status}
+	if status == types.QueryStatusComplete {
+		out.Results = f.results
+	}
+	return out, nil
+}
+
+func (f *fakeInsightsClient) StopQuery(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+	f.stopped = true
+	return &cloudwatchlogs.StopQueryOutput{Success: true}, nil
+}
+
+func TestNewStartQueryInput(t *testing.T) {
+	input := newStartQueryInput("stats count()", []string{"group", "group2"}, &cloudwatchlogs.FilterLogEventsInput{
+		StartTime: aws.Int64(1641135845000),
+		EndTime:   aws.Int64(1641139445999),
+		Limit:     aws.Int32(100),
+	})
+	assert.Equal(t, "stats count()", *input.QueryString)
+	assert.Equal(t, []string{"group", "group2"}, input.LogGroupNames)
+	assert.Equal(t, int64(1641135845), *input.StartTime)
+	assert.Equal(t, int64(1641139445), *input.EndTime)
+	assert.Equal(t, int32(100), *input.Limit)
+}
+
+func TestRunQuery(t *testing.T) {
+	queryPollInterval = time.Millisecond
+	input := &cloudwatchlogs.StartQueryInput{QueryString: aws.String("stats count()")}
+
+	t.Run("complete after polling", func(t *testing.T) {
+		client := &fakeInsightsClient{
+			statuses: []types.QueryStatus{types.QueryStatusScheduled, types.QueryStatusRunning, types.QueryStatusComplete},
+			results: [][]types.ResultField{
+				{{Field: aws.String("count()"), Value: aws.String("42")}},
+			},
+		}
+		results, err := runQuery(context.Background(), client, input)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, client.polls)
+		assert.Len(t, results, 1)
+		assert.Equal(t, "42", *results[0][0].Value)
+	})
+	t.Run("failed query", func(t *testing.T) {
+		client := &fakeInsightsClient{statuses: []types.QueryStatus{types.QueryStatusFailed}}
+		_, err := runQuery(context.Background(), client, input)
+		assert.EqualError(t, err, "query query-1 finished with status Failed")
+	})
+	t.Run("start error", func(t *testing.T) {
+		client := &fakeInsightsClient{startErr: errors.New("denied")}
+		_, err := runQuery(context.Background(), client, input)
+		assert.EqualError(t, err, "denied")
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		client := &fakeInsightsClient{}
+		_, err := runQuery(ctx, client, input)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.True(t, client.stopped)
+	})
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/steffakasid/lc/internal"
+)
+
+const (
+	rotateNameNumber = "number"
+	rotateNameTime   = "time"
+	// rotateTimeLayout is used for time-stamped chunk names.
+	rotateTimeLayout = "20060102T150405Z"
+	manifestSuffix   = ".manifest.json"
+)
+
+// chunk is a file of a rotated output as listed in the manifest.
+type chunk struct {
+	File   string     `json:"file"`
+	Start  *time.Time `json:"start,omitempty"`
+	End    *time.Time `json:"end,omitempty"`
+	Events int        `json:"events"`
+	Bytes  int64      `json:"bytes"`
+	// bucket is the start of the rotate interval of the chunk
+	bucket time.Time
+}
+
+// rotatingOutput writes to a new file whenever the current file reached maxSize or
+// an event belongs to the next rotate interval. Each file is listed with its time
+// range and number of events in a manifest which is saved after every rotation.
+type rotatingOutput struct {
+	// chunk files are named base, suffix and ext
+	base, ext string
+	maxSize   int64
+	interval  time.Duration
+	naming    string
+	manifest  string
+	current   *logOutput
+	chunks    []*chunk
+	names     map[string]bool
+	// err is the first error opening a chunk
+	err error
+}
+
+// newRotatingOutput returns an output which rotates the file outputFile. The
+// files are only created when the first event is written to them.
+func newRotatingOutput(outputFile string, maxSize int64, interval time.Duration, naming string) *rotatingOutput {
+	ext := outputExtension()
+	base := strings.TrimSuffix(outputFile, ext)
+	return &rotatingOutput{
+		base:     base,
+		ext:      ext,
+		maxSize:  maxSize,
+		interval: interval,
+		naming:   naming,
+		manifest: base + manifestSuffix,
+		names:    map[string]bool{},
+	}
+}
+
+func (r *rotatingOutput) write(log internal.Printable) {
+	ts, hasTime := eventTime(log)
+	if r.current != nil && r.rotationDue(ts, hasTime) {
+		CheckError(r.closeChunk(), logger.Errorf)
+	}
+	if r.current == nil {
+		if err := r.openChunk(ts, hasTime); err != nil {
+			if r.err == nil {
+				logger.Errorf("%s\n", err)
+				r.err = err
+			}
+			return
+		}
+	}
+
+	r.current.write(log)
+	c := r.chunks[len(r.chunks)-1]
+	c.Events++
+	if hasTime {
+		if c.Start == nil {
+			c.Start = &ts
+		}
+		c.End = &ts
+	}
+}
+
+// eventTime returns the timestamp of log if it has one. It's read from the event,
+// as the printed timestamp depends on the time format.
+func eventTime(log internal.Printable) (time.Time, bool) {
+	event, ok := log.(internal.Log)
+	if !ok || event.Timestamp == nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(*event.Timestamp).UTC(), true
+}
+
+func (r *rotatingOutput) rotationDue(ts time.Time, hasTime bool) bool {
+	if r.interval > 0 && hasTime && !ts.Truncate(r.interval).Equal(r.chunks[len(r.chunks)-1].bucket) {
+		return true
+	}
+	if r.maxSize > 0 {
+		return r.current.written() >= r.maxSize
+	}
+	return false
+}
+
+func (r *rotatingOutput) openChunk(ts time.Time, hasTime bool) error {
+	c := &chunk{}
+	if r.interval > 0 && hasTime {
+		c.bucket = ts.Truncate(r.interval)
+	}
+
+	suffix := fmt.Sprintf("-%04d", len(r.chunks)+1)
+	if r.naming == rotateNameTime {
+		switch {
+		case !c.bucket.IsZero():
+			suffix = "-" + c.bucket.Format(rotateTimeLayout)
+		case hasTime:
+			suffix = "-" + ts.Format(rotateTimeLayout)
+		default:
+			suffix = "-" + time.Now().UTC().Format(rotateTimeLayout)
+		}
+	}
+	name := r.base + suffix + r.ext
+	for i := 2; r.names[name]; i++ {
+		name = fmt.Sprintf("%s%s-%d%s", r.base, suffix, i, r.ext)
+	}
+
+	// chunks are never appended to, a chunk of an earlier export would end up in
+	// the wrong manifest
+	mode := ifExistsFail
+	if viper.GetString(ifExists) == ifExistsOverwrite {
+		mode = ifExistsOverwrite
+	} else if _, err := os.Stat(name); err == nil {
+		return fmt.Errorf("%s already exists, use --%s %s", name, ifExists, ifExistsOverwrite)
+	}
+	out, err := newLogOutput(name, mode, false)
+	if err != nil {
+		return err
+	}
+	r.names[name] = true
+	r.current = out
+	c.File = filepath.Base(name)
+	r.chunks = append(r.chunks, c)
+	return nil
+}
+
+// closeChunk closes the current file and saves the manifest.
+func (r *rotatingOutput) closeChunk() error {
+	err := r.current.Close()
+	if info, statErr := os.Stat(r.current.file.Name()); statErr == nil {
+		r.chunks[len(r.chunks)-1].Bytes = info.Size()
+	}
+	r.current = nil
+	return errors.Join(err, writeJSONFile(r.manifest, r.chunks))
+}
+
+func (r *rotatingOutput) flush() {
+	if r.current != nil {
+		r.current.flush()
+	}
+}
+
+// Close closes the current chunk.
+func (r *rotatingOutput) Close() error {
+	if r.current == nil {
+		return r.err
+	}
+	return errors.Join(r.err, r.closeChunk())
+}
+
+func (r *rotatingOutput) resumable() bool {
+	return false
+}
+
+// parseSize parses sizes like 100MB, 1.5GiB or 4096. KB, MB and GB are powers of
+// 1000, KiB, MiB and GiB powers of 1024.
+func parseSize(size string) (int64, error) {
+	units := []struct {
+		suffix string
+		factor float64
+	}{
+		{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30},
+		{"kb", 1e3}, {"mb", 1e6}, {"gb", 1e9},
+		{"k", 1e3}, {"m", 1e6}, {"g", 1e9}, {"b", 1},
+	}
+	s := strings.ToLower(strings.TrimSpace(size))
+	factor := 1.0
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			factor = unit.factor
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid size %s", size)
+	}
+	return int64(value * factor), nil
+}
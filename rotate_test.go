@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/viper"
+	"github.com/steffakasid/lc/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadManifest(t *testing.T, name string) []*chunk {
+	bt, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	chunks := []*chunk{}
+	assert.NoError(t, json.Unmarshal(bt, &chunks))
+	return chunks
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"4096":    4096,
+		"100MB":   100000000,
+		"100 mb":  100000000,
+		"1.5GiB":  1610612736,
+		"512KiB":  524288,
+		"2k":      2000,
+		"10b":     10,
+		"0.5 GB ": 500000000,
+	}
+	for size, expected := range tests {
+		parsed, err := parseSize(size)
+		assert.NoError(t, err, size)
+		assert.Equal(t, expected, parsed, size)
+	}
+	for _, size := range []string{"", "MB", "-1MB", "0", "ten"} {
+		_, err := parseSize(size)
+		assert.EqualError(t, err, "invalid size "+size)
+	}
+}
+
+func TestRotatingOutput(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	start := time.Date(2022, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	t.Run("by size", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "txt")
+		line := testLog("1", start.UnixMilli(), "stream", "message").FormatedLine()
+		// two events fit into a file
+		out := newRotatingOutput(path.Join(dir, "logs.txt"), int64(2*len(line)), 0, rotateNameNumber)
+		for i := 0; i < 5; i++ {
+			out.write(testLog(fmt.Sprint(i), start.Add(time.Duration(i)*time.Minute).UnixMilli(), "stream", "message"))
+		}
+		assert.NoError(t, out.Close())
+		assert.False(t, out.resumable())
+
+		for _, name := range []string{"logs-0001.txt", "logs-0002.txt", "logs-0003.txt"} {
+			assert.FileExists(t, path.Join(dir, name))
+		}
+		chunks := loadManifest(t, path.Join(dir, "logs"+manifestSuffix))
+		assert.Len(t, chunks, 3)
+		assert.Equal(t, "logs-0001.txt", chunks[0].File)
+		assert.Equal(t, 2, chunks[0].Events)
+		assert.Equal(t, int64(2*len(line)), chunks[0].Bytes)
+		assert.True(t, start.Equal(*chunks[0].Start))
+		assert.True(t, start.Add(time.Minute).Equal(*chunks[0].End))
+		assert.Equal(t, 1, chunks[2].Events)
+		viper.Reset()
+	})
+	t.Run("by size compressed", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "csv")
+		viper.Set(compress, compressGzip)
+		// the size is counted before compression and includes buffered rows
+		out := newRotatingOutput(path.Join(dir, "logs.csv.gz"), 40, 0, rotateNameNumber)
+		for i := 0; i < 5; i++ {
+			out.write(testLog(fmt.Sprint(i), start.Add(time.Duration(i)*time.Minute).UnixMilli(), "stream", "message"))
+		}
+		assert.NoError(t, out.Close())
+
+		chunks := loadManifest(t, path.Join(dir, "logs"+manifestSuffix))
+		assert.Len(t, chunks, 5)
+		content := decompress(t, compressGzip, path.Join(dir, "logs-0001.csv.gz"))
+		assert.Len(t, strings.Split(strings.TrimSpace(content), "\n"), 2)
+		viper.Reset()
+	})
+	t.Run("existing chunks", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "txt")
+		assert.NoError(t, os.WriteFile(path.Join(dir, "logs-0001.txt"), []byte("earlier export\n"), 0644))
+		out := newRotatingOutput(path.Join(dir, "logs.txt"), 0, 0, rotateNameNumber)
+		out.write(testLog("1", start.UnixMilli(), "stream", "message"))
+		assert.ErrorContains(t, out.Close(), "logs-0001.txt already exists, use --if-exists overwrite")
+
+		viper.Set(ifExists, ifExistsOverwrite)
+		out = newRotatingOutput(path.Join(dir, "logs.txt"), 0, 0, rotateNameNumber)
+		log := testLog("1", start.UnixMilli(), "stream", "message")
+		out.write(log)
+		assert.NoError(t, out.Close())
+		bt, err := os.ReadFile(path.Join(dir, "logs-0001.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, log.FormatedLine(), string(bt))
+		viper.Reset()
+	})
+	t.Run("by interval", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "jsonl")
+		out := newRotatingOutput(path.Join(dir, "logs.jsonl"), 0, time.Hour, rotateNameTime)
+		for i, offset := range []time.Duration{0, 30 * time.Minute, 90 * time.Minute, 3 * time.Hour} {
+			out.write(testLog(fmt.Sprint(i), start.Add(offset).UnixMilli(), "stream", "message"))
+		}
+		assert.NoError(t, out.Close())
+
+		chunks := loadManifest(t, path.Join(dir, "logs"+manifestSuffix))
+		files := []string{}
+		events := []int{}
+		for _, c := range chunks {
+			files = append(files, c.File)
+			events = append(events, c.Events)
+		}
+		assert.Equal(t, []string{"logs-20220102T150000Z.jsonl", "logs-20220102T160000Z.jsonl", "logs-20220102T180000Z.jsonl"}, files)
+		assert.Equal(t, []int{2, 1, 1}, events)
+		viper.Reset()
+	})
+	t.Run("by interval with time format", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "jsonl")
+		internal.SetTimeFormat(time.UTC, "rfc3339")
+		defer internal.SetTimeFormat(nil, "")
+		out := newRotatingOutput(path.Join(dir, "logs.jsonl"), 0, time.Hour, rotateNameNumber)
+		for i, offset := range []time.Duration{0, 90 * time.Minute} {
+			out.write(testLog(fmt.Sprint(i), start.Add(offset).UnixMilli(), "stream", "message"))
+		}
+		assert.NoError(t, out.Close())
+
+		chunks := loadManifest(t, path.Join(dir, "logs"+manifestSuffix))
+		assert.Len(t, chunks, 2)
+		assert.True(t, start.Equal(*chunks[0].Start))
+		assert.True(t, start.Add(90*time.Minute).Equal(*chunks[1].End))
+		viper.Reset()
+	})
+	t.Run("same time name", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "txt")
+		out := newRotatingOutput(path.Join(dir, "logs.txt"), 1, 0, rotateNameTime)
+		out.write(testLog("1", start.UnixMilli(), "stream", "message"))
+		out.write(testLog("2", start.UnixMilli(), "stream", "message"))
+		assert.NoError(t, out.Close())
+		assert.FileExists(t, path.Join(dir, "logs-20220102T150000Z.txt"))
+		assert.FileExists(t, path.Join(dir, "logs-20220102T150000Z-2.txt"))
+		viper.Reset()
+	})
+	t.Run("records without time", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "txt")
+		out := newRotatingOutput(path.Join(dir, "logs.txt"), 0, time.Hour, rotateNameNumber)
+		out.write(internal.Record{{Field: aws.String("count()"), Value: aws.String("1")}})
+		out.write(internal.Record{{Field: aws.String("count()"), Value: aws.String("2")}})
+		assert.NoError(t, out.Close())
+
+		chunks := loadManifest(t, path.Join(dir, "logs"+manifestSuffix))
+		assert.Len(t, chunks, 1)
+		assert.Nil(t, chunks[0].Start)
+		assert.Equal(t, 2, chunks[0].Events)
+		viper.Reset()
+	})
+}
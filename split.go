@@ -0,0 +1,153 @@
+package main
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/steffakasid/lc/internal"
+)
+
+const (
+	splitByStream = "stream"
+	// splitByFieldPrefix is followed by the path of a message field.
+	splitByFieldPrefix = "field:"
+	// splitNone is the file name for events without the split field.
+	splitNone = "_none"
+	// maxOpenSplitFiles limits the number of files kept open while splitting, so
+	// splitting by a field with many values doesn't run out of file handles.
+	maxOpenSplitFiles = 64
+)
+
+// splitOutput writes every event to a file per log stream or per value of a
+// message field. The files are created in a directory.
+type splitOutput struct {
+	dir string
+	// column is the csv column used to get the value to split by
+	column string
+	ext    string
+	// maxOpen is the number of files kept open, the least recently used file is
+	// closed when another one is opened and reopened for appending when needed
+	maxOpen int
+	// outputs contains the elements of the open files in recent
+	outputs map[string]*list.Element
+	// recent contains the open splitFiles, the most recently used first
+	recent *list.List
+	// created contains the keys of all files created by this output
+	created map[string]bool
+	// err is the first error opening a file
+	err error
+}
+
+type splitFile struct {
+	key string
+	out *logOutput
+}
+
+// splitColumn returns the csv column of the split-by value or an error if it's
+// invalid.
+func splitColumn(by string) (string, error) {
+	if by == splitByStream {
+		return "metadata.log-stream-name", nil
+	}
+	if field, ok := strings.CutPrefix(by, splitByFieldPrefix); ok && field != "" {
+		return field, nil
+	}
+	return "", fmt.Errorf("%s given but expected [%s, %s<path>]", by, splitByStream, splitByFieldPrefix)
+}
+
+// newSplitOutput returns an output which creates the files in a directory named
+// like outputFile without extensions. The files are only created when the first
+// event is written to them. A csv header is written to every new file, also when
+// resuming, as files which aren't empty are appended to without header.
+func newSplitOutput(outputFile, by string) (*splitOutput, error) {
+	column, err := splitColumn(by)
+	if err != nil {
+		return nil, err
+	}
+	ext := outputExtension()
+	return &splitOutput{
+		dir:     strings.TrimSuffix(outputFile, ext),
+		column:  column,
+		ext:     ext,
+		maxOpen: maxOpenSplitFiles,
+		outputs: map[string]*list.Element{},
+		recent:  list.New(),
+		created: map[string]bool{},
+	}, nil
+}
+
+func (s *splitOutput) write(log internal.Printable) {
+	key := splitNone
+	values, err := log.CsvValues([]string{s.column})
+	if err == nil && values[0] != "" {
+		key = values[0]
+	}
+
+	out, err := s.open(key)
+	if err != nil {
+		if s.err == nil {
+			logger.Errorf("%s\n", err)
+			s.err = err
+		}
+		return
+	}
+	out.write(log)
+}
+
+// open returns the output of key. A file which was closed before is reopened for
+// appending.
+func (s *splitOutput) open(key string) (*logOutput, error) {
+	if e, ok := s.outputs[key]; ok {
+		s.recent.MoveToFront(e)
+		return e.Value.(*splitFile).out, nil
+	}
+	if s.recent.Len() >= s.maxOpen {
+		if err := s.closeOldest(); err != nil {
+			return nil, err
+		}
+	}
+
+	mode := viper.GetString(ifExists)
+	if s.created[key] {
+		mode = ifExistsAppend
+	}
+	out, err := newLogOutput(filepath.Join(s.dir, fileNameReplacer.Replace(key)+s.ext), mode, false)
+	if err != nil {
+		return nil, err
+	}
+	s.created[key] = true
+	s.outputs[key] = s.recent.PushFront(&splitFile{key: key, out: out})
+	return out, nil
+}
+
+// closeOldest closes the least recently used file. Compressed files are continued
+// with a new gzip member or zstd frame when they are reopened.
+func (s *splitOutput) closeOldest() error {
+	f := s.recent.Remove(s.recent.Back()).(*splitFile)
+	delete(s.outputs, f.key)
+	return f.out.Close()
+}
+
+func (s *splitOutput) flush() {
+	for e := s.recent.Front(); e != nil; e = e.Next() {
+		e.Value.(*splitFile).out.flush()
+	}
+}
+
+// Close closes all open files.
+func (s *splitOutput) Close() error {
+	errs := []error{s.err}
+	for s.recent.Len() > 0 {
+		errs = append(errs, s.closeOldest())
+	}
+	return errors.Join(errs...)
+}
+
+func (s *splitOutput) resumable() bool {
+	return viper.GetString(compress) == ""
+}
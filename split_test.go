@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/steffakasid/lc/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitColumn(t *testing.T) {
+	column, err := splitColumn(splitByStream)
+	assert.NoError(t, err)
+	assert.Equal(t, "metadata.log-stream-name", column)
+
+	column, err = splitColumn("field:kubernetes.pod_name")
+	assert.NoError(t, err)
+	assert.Equal(t, "kubernetes.pod_name", column)
+
+	for _, by := range []string{"field:", "pod", ""} {
+		_, err = splitColumn(by)
+		assert.EqualError(t, err, by+" given but expected [stream, field:<path>]")
+	}
+}
+
+func TestSplitOutput(t *testing.T) {
+	t.Cleanup(viper.Reset)
+
+	t.Run("by stream", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "txt")
+		out, err := newSplitOutput(path.Join(dir, "logs.txt"), splitByStream)
+		assert.NoError(t, err)
+		assert.True(t, out.resumable())
+
+		first := testLog("1", 1000, "app/pod-a", "first")
+		second := testLog("1", 1000, "app/pod-b", "second")
+		third := testLog("1", 1000, "app/pod-a", "third")
+		for _, log := range []internal.Log{first, second, third} {
+			out.write(log)
+		}
+		assert.NoError(t, out.Close())
+
+		bt, err := os.ReadFile(path.Join(dir, "logs", "app-pod-a.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, first.FormatedLine()+third.FormatedLine(), string(bt))
+		bt, err = os.ReadFile(path.Join(dir, "logs", "app-pod-b.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, second.FormatedLine(), string(bt))
+		viper.Reset()
+	})
+	t.Run("by field", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "csv")
+		viper.Set(filterFields, []string{"log"})
+		out, err := newSplitOutput(path.Join(dir, "logs.csv"), "field:kubernetes.pod_name")
+		assert.NoError(t, err)
+
+		out.write(testLog("1", 1000, "stream", `{"kubernetes": {"pod_name": "pod-a"}, "log": "first"}`))
+		out.write(testLog("1", 1000, "stream", `{"kubernetes": {"pod_name": "pod-a"}, "log": "second"}`))
+		out.write(testLog("1", 1000, "stream", `START RequestId: 1234`))
+		out.flush()
+		assert.NoError(t, out.Close())
+
+		bt, err := os.ReadFile(path.Join(dir, "logs", "pod-a.csv"))
+		assert.NoError(t, err)
+		assert.Equal(t, "log\nfirst\nsecond\n", string(bt))
+		bt, err = os.ReadFile(path.Join(dir, "logs", splitNone+".csv"))
+		assert.NoError(t, err)
+		assert.Equal(t, "log\n\n", string(bt))
+		viper.Reset()
+	})
+	t.Run("resumed", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "csv")
+		viper.Set(filterFields, []string{"log"})
+		viper.Set(ifExists, ifExistsAppend)
+		assert.NoError(t, os.MkdirAll(path.Join(dir, "logs"), 0755))
+		assert.NoError(t, os.WriteFile(path.Join(dir, "logs", "pod-a.csv"), []byte("log\nfirst\n"), 0644))
+		out, err := newSplitOutput(path.Join(dir, "logs.csv"), "field:pod")
+		assert.NoError(t, err)
+
+		out.write(testLog("1", 1000, "stream", `{"pod": "pod-a", "log": "second"}`))
+		out.write(testLog("1", 1000, "stream", `{"pod": "new-pod", "log": "third"}`))
+		assert.NoError(t, out.Close())
+
+		bt, err := os.ReadFile(path.Join(dir, "logs", "pod-a.csv"))
+		assert.NoError(t, err)
+		assert.Equal(t, "log\nfirst\nsecond\n", string(bt))
+		// files of values first seen after resuming get a header
+		bt, err = os.ReadFile(path.Join(dir, "logs", "new-pod.csv"))
+		assert.NoError(t, err)
+		assert.Equal(t, "log\nthird\n", string(bt))
+		viper.Reset()
+	})
+	t.Run("compressed", func(t *testing.T) {
+		viper.Set(outputFormat, "jsonl")
+		viper.Set(compress, compressGzip)
+		out, err := newSplitOutput(path.Join(t.TempDir(), "logs.jsonl.gz"), splitByStream)
+		assert.NoError(t, err)
+		assert.Equal(t, ".jsonl.gz", out.ext)
+		assert.Equal(t, "logs", path.Base(out.dir))
+		assert.False(t, out.resumable())
+		viper.Reset()
+	})
+	t.Run("more files than open handles", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "csv")
+		viper.Set(filterFields, []string{"log"})
+		viper.Set(compress, compressGzip)
+		viper.Set(ifExists, ifExistsFail)
+		out, err := newSplitOutput(path.Join(dir, "logs.csv.gz"), "field:pod")
+		assert.NoError(t, err)
+		out.maxOpen = 2
+
+		for i, pod := range []string{"a", "b", "c", "a", "b", "c", "a"} {
+			out.write(testLog("1", 1000, "stream", fmt.Sprintf(`{"pod": %q, "log": "%d"}`, pod, i)))
+			assert.LessOrEqual(t, out.recent.Len(), 2)
+		}
+		assert.NoError(t, out.Close())
+
+		// reopened files are appended to with a new gzip member and without header
+		assert.Equal(t, "log\n0\n3\n6\n", decompress(t, compressGzip, path.Join(dir, "logs", "a.csv.gz")))
+		assert.Equal(t, "log\n1\n4\n", decompress(t, compressGzip, path.Join(dir, "logs", "b.csv.gz")))
+		assert.Equal(t, "log\n2\n5\n", decompress(t, compressGzip, path.Join(dir, "logs", "c.csv.gz")))
+		viper.Reset()
+	})
+	t.Run("file can't be opened", func(t *testing.T) {
+		dir := t.TempDir()
+		viper.Set(outputFormat, "txt")
+		viper.Set(ifExists, ifExistsFail)
+		assert.NoError(t, os.MkdirAll(path.Join(dir, "logs"), 0755))
+		assert.NoError(t, os.WriteFile(path.Join(dir, "logs", "pod-a.txt"), []byte("old\n"), 0644))
+		out, err := newSplitOutput(path.Join(dir, "logs.txt"), "field:pod")
+		assert.NoError(t, err)
+
+		out.write(testLog("1", 1000, "stream", `{"pod": "pod-a"}`))
+		out.write(testLog("1", 1000, "stream", `{"pod": "pod-b"}`))
+		out.write(testLog("1", 1000, "stream", `{"pod": "pod-a"}`))
+		err = out.Close()
+		assert.ErrorContains(t, err, "pod-a.txt already exists")
+		assert.FileExists(t, path.Join(dir, "logs", "pod-b.txt"))
+		viper.Reset()
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	// timezone works without zoneinfo installed
+	_ "time/tzdata"
+
+	"github.com/xhit/go-str2duration/v2"
+)
+
+var (
+	// relativeTime matches e.g. now-2h, now-1d/d, today or yesterday 14:00. Only the
+	// keywords ignore case, units don't, so now/M isn't taken for minutes.
+	relativeTime = regexp.MustCompile(`^(?i:(now|today|yesterday))(?:\s+(\d{1,2}:\d{2}(?::\d{2})?))?((?:\s*[+-]\s*[0-9][0-9a-zµ.]*)*)(?:\s*/\s*([smhdw]))?$`)
+	timeOffset   = regexp.MustCompile(`([+-])\s*([0-9][0-9a-zµ.]*)`)
+	timeOfDay    = regexp.MustCompile(`^\d{1,2}:\d{2}(?::\d{2})?$`)
+	epoch        = regexp.MustCompile(`^\d+$`)
+)
+
+// localLayouts are parsed in the location given by timezone.
+var localLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTime parses expr as:
+//
+//	RFC3339 time            2006-01-02T15:04:05Z or 2006-01-02T15:04:05+07:00
+//	local time              2006-01-02 15:04:05, 2006-01-02 15:04 or 2006-01-02
+//	time of day             09:30 or 09:30:15, yesterday if it's later than now
+//	epoch                   seconds or milliseconds, e.g. 1641135845
+//	relative time           now-2h, today, yesterday 14:00 or today-1w
+//	rounded relative time   now/h or now-1d/d
+//
+// Times without an offset are in loc. Relative times are based on now.
+func parseTime(expr string, now time.Time, loc *time.Location) (time.Time, error) {
+	s := strings.TrimSpace(expr)
+	now = now.In(loc)
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	if timeOfDay.MatchString(s) {
+		t, err := atTimeOfDay(now, s)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// 23:00 in the morning means yesterday evening, logs of the future don't exist
+		if t.After(now) {
+			t = t.AddDate(0, 0, -1)
+		}
+		return t, nil
+	}
+	if epoch.MatchString(s) {
+		value, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// seconds would be after the year 5138
+		if value >= 1e11 {
+			return time.UnixMilli(value).In(loc), nil
+		}
+		return time.Unix(value, 0).In(loc), nil
+	}
+
+	match := relativeTime.FindStringSubmatch(s)
+	if match == nil {
+		return time.Time{}, fmt.Errorf("can't parse time %s", expr)
+	}
+	t := now
+	switch strings.ToLower(match[1]) {
+	case "today":
+		t = startOfDay(now)
+	case "yesterday":
+		t = startOfDay(now).AddDate(0, 0, -1)
+	}
+	if match[2] != "" {
+		if strings.EqualFold(match[1], "now") {
+			return time.Time{}, fmt.Errorf("can't parse time %s, use today %s", expr, match[2])
+		}
+		var err error
+		if t, err = atTimeOfDay(t, match[2]); err != nil {
+			return time.Time{}, err
+		}
+	}
+	for _, offset := range timeOffset.FindAllStringSubmatch(match[3], -1) {
+		d, err := str2duration.ParseDuration(offset[2])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("can't parse time %s: %w", expr, err)
+		}
+		if offset[1] == "-" {
+			d = -d
+		}
+		t = t.Add(d)
+	}
+	if match[4] != "" {
+		t = roundDown(t, match[4])
+	}
+	return t, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// atTimeOfDay returns the time clock like 09:30 or 09:30:15 on the day of t.
+func atTimeOfDay(t time.Time, clock string) (time.Time, error) {
+	parts := strings.Split(clock, ":")
+	values := make([]int, 3)
+	for i, part := range parts {
+		values[i], _ = strconv.Atoi(part)
+	}
+	if values[0] > 23 || values[1] > 59 || values[2] > 59 {
+		return time.Time{}, fmt.Errorf("invalid time of day %s", clock)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), values[0], values[1], values[2], 0, t.Location()), nil
+}
+
+// roundDown rounds t down to the start of the second, minute, hour, day or week
+// (starting on Monday).
+func roundDown(t time.Time, unit string) time.Time {
+	switch unit {
+	case "s":
+		return t.Truncate(time.Second)
+	case "m":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	case "h":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case "d":
+		return startOfDay(t)
+	case "w":
+		return startOfDay(t).AddDate(0, 0, -(int(t.Weekday())+6)%7)
+	}
+	return t
+}
+
+// loadTimezone returns the location name or Local if name is empty.
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	// a Wednesday
+	now := time.Date(2022, 1, 5, 15, 4, 5, 6000000, berlin)
+
+	tests := map[string]time.Time{
+		"2022-01-02T15:04:05Z":            time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC),
+		"2022-01-02T15:04:05+07:00":       time.Date(2022, 1, 2, 8, 4, 5, 0, time.UTC),
+		"2022-01-02T15:04:05.123Z":        time.Date(2022, 1, 2, 15, 4, 5, 123000000, time.UTC),
+		"2022-01-02 15:04:05":             time.Date(2022, 1, 2, 15, 4, 5, 0, berlin),
+		"2022-01-02T15:04":                time.Date(2022, 1, 2, 15, 4, 0, 0, berlin),
+		"2022-01-02":                      time.Date(2022, 1, 2, 0, 0, 0, 0, berlin),
+		"09:30":                           time.Date(2022, 1, 5, 9, 30, 0, 0, berlin),
+		"9:30:15":                         time.Date(2022, 1, 5, 9, 30, 15, 0, berlin),
+		"15:04:05":                        time.Date(2022, 1, 5, 15, 4, 5, 0, berlin),
+		"23:00":                           time.Date(2022, 1, 4, 23, 0, 0, 0, berlin),
+		"today 23:00":                     time.Date(2022, 1, 5, 23, 0, 0, 0, berlin),
+		"1641135845":                      time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC),
+		"1641135845123":                   time.Date(2022, 1, 2, 15, 4, 5, 123000000, time.UTC),
+		"now":                             now,
+		"NOW-2h":                          now.Add(-2 * time.Hour),
+		"Yesterday":                       time.Date(2022, 1, 4, 0, 0, 0, 0, berlin),
+		"now - 1d + 30m":                  now.Add(-24*time.Hour + 30*time.Minute),
+		"now/h":                           time.Date(2022, 1, 5, 15, 0, 0, 0, berlin),
+		"now-1d/d":                        time.Date(2022, 1, 4, 0, 0, 0, 0, berlin),
+		"now/w":                           time.Date(2022, 1, 3, 0, 0, 0, 0, berlin),
+		"now/m":                           time.Date(2022, 1, 5, 15, 4, 0, 0, berlin),
+		"now/s":                           time.Date(2022, 1, 5, 15, 4, 5, 0, berlin),
+		"today":                           time.Date(2022, 1, 5, 0, 0, 0, 0, berlin),
+		"today-1w":                        time.Date(2021, 12, 29, 0, 0, 0, 0, berlin),
+		"yesterday":                       time.Date(2022, 1, 4, 0, 0, 0, 0, berlin),
+		"yesterday 14:00":                 time.Date(2022, 1, 4, 14, 0, 0, 0, berlin),
+		"yesterday 14:00+90m":             time.Date(2022, 1, 4, 15, 30, 0, 0, berlin),
+		" today 09:30 ":                   time.Date(2022, 1, 5, 9, 30, 0, 0, berlin),
+		"2022-01-02T15:04:05.999999999Z ": time.Date(2022, 1, 2, 15, 4, 5, 999999999, time.UTC),
+	}
+	for expr, expected := range tests {
+		t.Run(expr, func(t *testing.T) {
+			parsed, err := parseTime(expr, now, berlin)
+			assert.NoError(t, err)
+			assert.True(t, expected.Equal(parsed), "expected %s but got %s", expected, parsed)
+		})
+	}
+
+	for expr, msg := range map[string]string{
+		"tomorrow":    "can't parse time tomorrow",
+		"now 14:00":   "can't parse time now 14:00, use today 14:00",
+		"25:00":       "invalid time of day 25:00",
+		"now-2x":      "can't parse time now-2x: time: unknown unit \"x\" in duration \"2x\"",
+		"2022-13-01":  "can't parse time 2022-13-01",
+		"now/q":       "can't parse time now/q",
+		"now/M":       "can't parse time now/M",
+		"now-2H":      "can't parse time now-2H",
+		"yesterday-1": "can't parse time yesterday-1: time: missing unit in duration \"1\"",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := parseTime(expr, now, berlin)
+			assert.EqualError(t, err, msg)
+		})
+	}
+}
+
+func TestLoadTimezone(t *testing.T) {
+	loc, err := loadTimezone("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Local, loc)
+
+	loc, err = loadTimezone("UTC")
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	_, err = loadTimezone("Mars/Olympus")
+	assert.Error(t, err)
+}